@@ -0,0 +1,209 @@
+// Package accesslog parses web server access logs. Formats are written in
+// the style of nginx's log_format directive, as literal text and $variable
+// references, and compiled into a parser for lines in that format.
+package accesslog
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"parsec"
+)
+
+type Record struct {
+	RemoteAddr net.IP
+	Ident      string
+	User       string
+	Time       time.Time
+	// Request is the request line, which is also split into Method, Path
+	// and Protocol if it has three parts.
+	Request   string
+	Method    string
+	Path      string
+	Protocol  string
+	Status    int
+	Bytes     int64
+	Referer   string
+	UserAgent string
+	// Fields holds the values of variables with no field of their own.
+	Fields map[string]string
+}
+
+// The Common Log Format and the Combined Log Format of Apache and nginx.
+const (
+	CommonFormat   = `$remote_addr $ident $remote_user [$time_local] "$request" $status $body_bytes_sent`
+	CombinedFormat = CommonFormat + ` "$http_referer" "$http_user_agent"`
+)
+
+var Common = MustFormat(CommonFormat)
+var Combined = MustFormat(CombinedFormat)
+
+type setter func(r *Record, value string) error
+
+func str(field func(*Record) *string) setter {
+	return func(r *Record, value string) error {
+		if value != "-" {
+			*field(r) = value
+		}
+		return nil
+	}
+}
+
+var variables = map[string]setter{
+	"remote_addr": func(r *Record, value string) error {
+		if r.RemoteAddr = net.ParseIP(value); r.RemoteAddr == nil {
+			return fmt.Errorf("Invalid IP address %s", value)
+		}
+		return nil
+	},
+	"ident":       str(func(r *Record) *string { return &r.Ident }),
+	"remote_user": str(func(r *Record) *string { return &r.User }),
+	"time_local": func(r *Record, value string) (err error) {
+		if r.Time, err = time.Parse("02/Jan/2006:15:04:05 -0700", value); err != nil {
+			return fmt.Errorf("Invalid time %s", value)
+		}
+		return nil
+	},
+	"request": func(r *Record, value string) error {
+		r.Request = value
+		if parts := strings.Split(value, " "); len(parts) == 3 {
+			r.Method, r.Path, r.Protocol = parts[0], parts[1], parts[2]
+		}
+		return nil
+	},
+	"status": func(r *Record, value string) (err error) {
+		if r.Status, err = strconv.Atoi(value); err != nil || r.Status < 100 || r.Status > 999 {
+			return fmt.Errorf("Invalid status %s", value)
+		}
+		return nil
+	},
+	"body_bytes_sent": bytes,
+	"bytes_sent":      bytes,
+	"http_referer":    str(func(r *Record) *string { return &r.Referer }),
+	"http_user_agent": str(func(r *Record) *string { return &r.UserAgent }),
+}
+
+func bytes(r *Record, value string) (err error) {
+	if value == "-" {
+		return nil
+	}
+	if r.Bytes, err = strconv.ParseInt(value, 10, 64); err != nil || r.Bytes < 0 {
+		return fmt.Errorf("Invalid byte count %s", value)
+	}
+	return nil
+}
+
+func isNameChar(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '_'
+}
+
+// variable returns a parser for the value of the named variable, which
+// extends up to stop or the end of the line, and yields a function
+// storing it in a record.
+func variable(name string, stop byte) parsec.Parser {
+	set, ok := variables[name]
+	if !ok {
+		set = func(r *Record, value string) error {
+			if r.Fields == nil {
+				r.Fields = make(map[string]string)
+			}
+			r.Fields[name] = value
+			return nil
+		}
+	}
+	stops := []byte{'\r', '\n'}
+	if stop != 0 {
+		stops = append(stops, stop)
+	}
+	return parsec.Many(parsec.NoneOf(stops)).ToString().WithSpan().Bind(func(x interface{}) parsec.Parser {
+		s := x.(parsec.Spanned)
+		return func(st *parsec.ParseState) (interface{}, error) {
+			return func(r *Record) error {
+				if err := set(r, s.Value.(string)); err != nil {
+					return st.ErrorAt(s.Span.Start, "%s", err)
+				}
+				return nil
+			}, nil
+		}
+	})
+}
+
+// Format compiles a log format into a parser for one line, which yields a
+// *Record. Each variable's value extends up to the first character of the
+// literal text following it, so two variables may not be adjacent.
+// Variables without a field of their own are stored in Record.Fields.
+func Format(format string) (parsec.Parser, error) {
+	var steps []parsec.Parser
+	for i := 0; i < len(format); {
+		if format[i] != '$' {
+			n := strings.IndexByte(format[i:], '$')
+			if n < 0 {
+				n = len(format) - i
+			}
+			steps = append(steps, parsec.String(format[i:i+n]).Then(parsec.Return(nil)))
+			i += n
+			continue
+		}
+		j := i + 1
+		for j < len(format) && isNameChar(format[j]) {
+			j++
+		}
+		if j == i+1 {
+			return nil, fmt.Errorf("accesslog: missing variable name at offset %d", i)
+		}
+		var stop byte
+		if j < len(format) {
+			if format[j] == '$' {
+				return nil, fmt.Errorf("accesslog: adjacent variables at offset %d", j)
+			}
+			stop = format[j]
+		}
+		steps = append(steps, variable(format[i+1:j], stop))
+		i = j
+	}
+	line := parsec.Return([]interface{}{})
+	for _, step := range steps {
+		step := step
+		line = line.Bind(func(xs interface{}) parsec.Parser {
+			return step.Bind(func(x interface{}) parsec.Parser {
+				return parsec.Return(append(xs.([]interface{}), x))
+			})
+		})
+	}
+	return line.Bind(func(xs interface{}) parsec.Parser {
+		return parsec.Eol.Label("end of line").Then(func(st *parsec.ParseState) (interface{}, error) {
+			r := &Record{}
+			for _, x := range xs.([]interface{}) {
+				if set, ok := x.(func(*Record) error); ok {
+					if err := set(r); err != nil {
+						return nil, err
+					}
+				}
+			}
+			return r, nil
+		})
+	}), nil
+}
+
+// MustFormat is like Format but panics if the format is invalid.
+func MustFormat(format string) parsec.Parser {
+	p, err := Format(format)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+var either = parsec.Try(Combined).Or(Common)
+
+// Parse parses a line in the Combined or Common Log Format.
+func Parse(line string) (*Record, error) {
+	x, err := either.Parse(line)
+	if err != nil {
+		return nil, err
+	}
+	return x.(*Record), nil
+}
@@ -0,0 +1,213 @@
+// Package ansi splits terminal output into runs of text and the ANSI/VT
+// escape sequences between them: control sequences (CSI), such as those
+// selecting graphic renditions or moving the cursor, operating system
+// commands (OSC), such as window titles and hyperlinks, and other escape
+// sequences.
+package ansi
+
+import (
+	"strconv"
+	"strings"
+
+	"parsec"
+)
+
+type Kind int
+
+const (
+	Text Kind = iota
+	CSI
+	OSC
+	Escape
+)
+
+type Token struct {
+	Kind Kind
+	// Text is the token's source text.
+	Text string
+	// Private is a CSI's private parameter marker: '?', '>', '<' or '=',
+	// or 0.
+	Private byte
+	// Params holds a CSI's parameters, separated by ; or :, with -1 for
+	// those left empty.
+	Params []int
+	// Intermediate holds the intermediate bytes of a CSI or escape
+	// sequence.
+	Intermediate string
+	// Final is the final byte of a CSI or escape sequence.
+	Final byte
+	// Data is the payload of an OSC, without its terminator.
+	Data string
+	Span parsec.Span
+}
+
+// Param returns the i'th parameter of a CSI, or def if it is absent or
+// empty. Most commands treat 0 as the default too, so def also replaces 0
+// unless it is 0 itself.
+func (t Token) Param(i, def int) int {
+	if i >= len(t.Params) || t.Params[i] < 0 || t.Params[i] == 0 && def != 0 {
+		return def
+	}
+	return t.Params[i]
+}
+
+var commands = map[byte]string{
+	'A': "CUU", 'B': "CUD", 'C': "CUF", 'D': "CUB", 'E': "CNL", 'F': "CPL",
+	'G': "CHA", 'H': "CUP", 'f': "HVP", 'J': "ED", 'K': "EL", 'S': "SU", 'T': "SD",
+	'm': "SGR", 's': "SCP", 'u': "RCP",
+}
+
+// Command returns the mnemonic of a standard CSI, such as SGR (select
+// graphic rendition) or CUU (cursor up), or "" if it has none.
+func (t Token) Command() string {
+	if t.Kind != CSI || t.Private != 0 || t.Intermediate != "" {
+		return ""
+	}
+	return commands[t.Final]
+}
+
+// text parses the run of text up to the next escape.
+func text(st *parsec.ParseState) (interface{}, error) {
+	n := strings.IndexByte(st.Source[st.Pos:], '\x1b')
+	if n < 0 {
+		n = len(st.Source) - st.Pos
+	}
+	if n == 0 {
+		return parsec.Fail("Expected text")(st)
+	}
+	s := st.Source[st.Pos : st.Pos+n]
+	// Step over the run a byte at a time to keep line numbers.
+	for end := st.Pos + n; st.Pos < end; {
+		parsec.AnyChar(st)
+	}
+	return Token{Kind: Text, Text: s}, nil
+}
+
+func between(lo, hi byte) func(byte) bool {
+	return func(c byte) bool { return lo <= c && c <= hi }
+}
+
+// span returns the length of the prefix of s whose bytes satisfy pred.
+func span(s string, pred func(byte) bool) int {
+	n := 0
+	for n < len(s) && pred(s[n]) {
+		n++
+	}
+	return n
+}
+
+// malformed reports a malformed escape sequence at its start. Since no
+// input has been consumed, it uses parsec.Cut to keep the error from being
+// taken for the absence of a sequence.
+func malformed(st *parsec.ParseState, start parsec.Position, reason string) (interface{}, error) {
+	return parsec.Cut(func(st *parsec.ParseState) (interface{}, error) {
+		return nil, st.ErrorAt(start, "%s", reason)
+	})(st)
+}
+
+// escape parses an escape sequence, reporting a malformed one at its
+// start.
+func escape(st *parsec.ParseState) (interface{}, error) {
+	start, s := st.Position(), st.Source[st.Pos:]
+	if len(s) == 0 || s[0] != '\x1b' {
+		return parsec.Fail("Expected escape sequence")(st)
+	}
+	var t Token
+	var n int
+	switch {
+	case len(s) > 1 && s[1] == '[':
+		t.Kind = CSI
+		n = 2
+		params := span(s[n:], between(0x30, 0x3f))
+		p := s[n : n+params]
+		if p != "" && strings.IndexByte("?><=", p[0]) >= 0 {
+			t.Private, p = p[0], p[1:]
+		}
+		if p != "" {
+			for _, f := range strings.Split(strings.ReplaceAll(p, ":", ";"), ";") {
+				v, err := strconv.Atoi(f)
+				if err != nil {
+					v = -1
+				}
+				t.Params = append(t.Params, v)
+			}
+		}
+		n += params
+		inter := span(s[n:], between(0x20, 0x2f))
+		t.Intermediate = s[n : n+inter]
+		n += inter
+		if n == len(s) || s[n] < 0x40 || s[n] > 0x7e {
+			return malformed(st, start, "Unterminated control sequence")
+		}
+		t.Final = s[n]
+		n++
+	case len(s) > 1 && s[1] == ']':
+		t.Kind = OSC
+		// An OSC ends with BEL or with the string terminator ESC \.
+		bel := strings.IndexByte(s[2:], '\a')
+		term := strings.Index(s[2:], "\x1b\\")
+		switch {
+		case bel >= 0 && (term < 0 || bel < term):
+			t.Data, n = s[2:2+bel], 2+bel+1
+		case term >= 0:
+			t.Data, n = s[2:2+term], 2+term+2
+		default:
+			return malformed(st, start, "Unterminated operating system command")
+		}
+	default:
+		t.Kind = Escape
+		n = 1
+		inter := span(s[n:], between(0x20, 0x2f))
+		t.Intermediate = s[n : n+inter]
+		n += inter
+		if n == len(s) || s[n] < 0x30 || s[n] > 0x7e {
+			return malformed(st, start, "Unterminated escape sequence")
+		}
+		t.Final = s[n]
+		n++
+	}
+	t.Text = s[:n]
+	for end := st.Pos + n; st.Pos < end; {
+		parsec.AnyChar(st)
+	}
+	return t, nil
+}
+
+// Tokens parses text and escape sequences up to the end of input and
+// returns a []Token.
+var Tokens = parsec.Many(parsec.Parser(text).Or(escape).WithSpan().Bind(func(x interface{}) parsec.Parser {
+	s := x.(parsec.Spanned)
+	t := s.Value.(Token)
+	t.Span = s.Span
+	return parsec.Return(t)
+})).Bind(func(x interface{}) parsec.Parser {
+	tokens := make([]Token, len(x.([]interface{})))
+	for i, t := range x.([]interface{}) {
+		tokens[i] = t.(Token)
+	}
+	return parsec.Parser(parsec.Eof).Then(parsec.Return(tokens))
+})
+
+// Parse tokenizes s.
+func Parse(s string) ([]Token, error) {
+	x, err := Tokens.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	return x.([]Token), nil
+}
+
+// Strip returns s without its escape sequences. Of a malformed sequence
+// only the escape character is removed.
+func Strip(s string) string {
+	var sb strings.Builder
+	st := parsec.ParseState{Source: s, Line: 1}
+	for st.Pos < len(s) {
+		if x, err := text(&st); err == nil {
+			sb.WriteString(x.(Token).Text)
+		} else if _, err := escape(&st); err != nil {
+			st.Pos++
+		}
+	}
+	return sb.String()
+}
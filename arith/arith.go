@@ -0,0 +1,300 @@
+// Package arith parses arithmetic expressions with variables and function
+// calls into a positioned syntax tree, and evaluates them. Its grammar is
+// built with parsec.BuildExpression from the precedence table below, from
+// tightest to loosest binding:
+//
+//	^                   right associative
+//	- + ! (prefix)
+//	* / %               left associative
+//	+ -                 left associative
+//	< <= > >=           non-associative
+//	== !=               non-associative
+//	&&                  left associative
+//	||                  left associative
+//
+// Comparisons and logical operators yield 1 for true and 0 for false.
+package arith
+
+import (
+	"fmt"
+	"math"
+
+	"parsec"
+)
+
+type Kind int
+
+const (
+	Number Kind = iota
+	Variable
+	Call
+	Unary
+	Binary
+)
+
+type Node struct {
+	Kind  Kind
+	Value float64 // for numbers
+	// Name is the variable, function or operator name.
+	Name string
+	// Args holds the operands of an operator or the arguments of a call.
+	Args []*Node
+	Span parsec.Span
+}
+
+var ws = parsec.SkipMany(parsec.OneOf([]byte(" \t\r\n")))
+
+func lexeme(p parsec.Parser) parsec.Parser {
+	return p.Bind(func(x interface{}) parsec.Parser {
+		return ws.Then(parsec.Return(x))
+	})
+}
+
+func isIdentStart(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '_'
+}
+
+func identifier(st *parsec.ParseState) (interface{}, error) {
+	begin := st.Pos
+	if st.Pos >= len(st.Source) || !isIdentStart(st.Source[st.Pos]) {
+		return parsec.Fail("Expected identifier")(st)
+	}
+	for st.Pos++; st.Pos < len(st.Source) && (isIdentStart(st.Source[st.Pos]) || '0' <= st.Source[st.Pos] && st.Source[st.Pos] <= '9'); st.Pos++ {
+	}
+	return st.Source[begin:st.Pos], nil
+}
+
+// number parses an unsigned number; signs are prefix operators.
+func number(st *parsec.ParseState) (interface{}, error) {
+	if st.Pos >= len(st.Source) || st.Source[st.Pos] != '.' && (st.Source[st.Pos] < '0' || st.Source[st.Pos] > '9') {
+		return parsec.Fail("Expected number")(st)
+	}
+	x, err := parsec.Float(st)
+	if err != nil {
+		return nil, err
+	}
+	return &Node{Kind: Number, Value: x.(parsec.FloatLit).Value}, nil
+}
+
+func binary(op string) parsec.Parser {
+	return lexeme(parsec.String(op)).Then(parsec.Return(func(x, y interface{}) interface{} {
+		l, r := x.(*Node), y.(*Node)
+		return &Node{Kind: Binary, Name: op, Args: []*Node{l, r}, Span: parsec.Span{Start: l.Span.Start, End: r.Span.End}}
+	}))
+}
+
+func unary(op string) parsec.Parser {
+	return lexeme(parsec.String(op).WithSpan()).Bind(func(x interface{}) parsec.Parser {
+		start := x.(parsec.Spanned).Span.Start
+		return parsec.Return(func(x interface{}) interface{} {
+			n := x.(*Node)
+			return &Node{Kind: Unary, Name: op, Args: []*Node{n}, Span: parsec.Span{Start: start, End: n.Span.End}}
+		})
+	})
+}
+
+var table = [][]parsec.Operator{
+	{{Fixity: parsec.InfixRight, Op: binary("^")}},
+	{{Fixity: parsec.Prefix, Op: unary("-")}, {Fixity: parsec.Prefix, Op: unary("+")}, {Fixity: parsec.Prefix, Op: unary("!")}},
+	{{Fixity: parsec.InfixLeft, Op: binary("*")}, {Fixity: parsec.InfixLeft, Op: binary("/")}, {Fixity: parsec.InfixLeft, Op: binary("%")}},
+	{{Fixity: parsec.InfixLeft, Op: binary("+")}, {Fixity: parsec.InfixLeft, Op: binary("-")}},
+	{{Fixity: parsec.InfixNone, Op: binary("<=")}, {Fixity: parsec.InfixNone, Op: binary("<")}, {Fixity: parsec.InfixNone, Op: binary(">=")}, {Fixity: parsec.InfixNone, Op: binary(">")}},
+	{{Fixity: parsec.InfixNone, Op: binary("==")}, {Fixity: parsec.InfixNone, Op: binary("!=")}},
+	{{Fixity: parsec.InfixLeft, Op: binary("&&")}},
+	{{Fixity: parsec.InfixLeft, Op: binary("||")}},
+}
+
+// Expression parses an expression followed by any whitespace and returns a
+// *Node.
+var Expression parsec.Parser
+
+func init() {
+	exprRef := parsec.Parser(func(st *parsec.ParseState) (interface{}, error) {
+		return Expression(st)
+	})
+	call := func(name string) parsec.Parser {
+		return exprRef.SepBy(lexeme(parsec.Char(','))).Between(lexeme(parsec.Char('(')), parsec.Char(')').Label("')'")).Bind(func(x interface{}) parsec.Parser {
+			n := &Node{Kind: Call, Name: name, Args: []*Node{}}
+			for _, a := range x.([]interface{}) {
+				n.Args = append(n.Args, a.(*Node))
+			}
+			return parsec.Return(n)
+		})
+	}
+	name := parsec.Parser(identifier).Bind(func(x interface{}) parsec.Parser {
+		return call(x.(string)).Or(parsec.Return(&Node{Kind: Variable, Name: x.(string)}))
+	})
+	parens := exprRef.Between(lexeme(parsec.Char('(')), parsec.Char(')').Label("')'"))
+	// Terms span their parentheses, if any.
+	term := parsec.Parser(number).Or(name).Or(parens).Label("operand").WithSpan().Bind(func(x interface{}) parsec.Parser {
+		s := x.(parsec.Spanned)
+		n := s.Value.(*Node)
+		n.Span = s.Span
+		return parsec.Return(n)
+	})
+	Expression = parsec.BuildExpression(table, lexeme(term))
+}
+
+// Parse parses a complete expression.
+func Parse(source string) (*Node, error) {
+	x, err := ws.Then(Expression).Bind(func(x interface{}) parsec.Parser {
+		return parsec.Parser(parsec.Eof).Then(parsec.Return(x))
+	}).Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	return x.(*Node), nil
+}
+
+// Error is an evaluation error, located at the node that caused it.
+type Error struct {
+	Reason string
+	Span   parsec.Span
+}
+
+func (err Error) Error() string {
+	return fmt.Sprintf("%s on line %d", err.Reason, err.Span.Start.Line)
+}
+
+// Env supplies the variables and functions an expression may use.
+type Env struct {
+	Vars  map[string]float64
+	Funcs map[string]func(args ...float64) (float64, error)
+}
+
+// Functions holds the functions available to every expression, in
+// addition to those in the Env.
+var Functions = map[string]func(args ...float64) (float64, error){
+	"abs":   unaryFunc(math.Abs),
+	"sqrt":  unaryFunc(math.Sqrt),
+	"exp":   unaryFunc(math.Exp),
+	"log":   unaryFunc(math.Log),
+	"sin":   unaryFunc(math.Sin),
+	"cos":   unaryFunc(math.Cos),
+	"tan":   unaryFunc(math.Tan),
+	"floor": unaryFunc(math.Floor),
+	"ceil":  unaryFunc(math.Ceil),
+	"min":   fold(math.Min),
+	"max":   fold(math.Max),
+}
+
+func unaryFunc(f func(float64) float64) func(args ...float64) (float64, error) {
+	return func(args ...float64) (float64, error) {
+		if len(args) != 1 {
+			return 0, fmt.Errorf("expects 1 argument, got %d", len(args))
+		}
+		return f(args[0]), nil
+	}
+}
+
+func fold(f func(x, y float64) float64) func(args ...float64) (float64, error) {
+	return func(args ...float64) (float64, error) {
+		if len(args) == 0 {
+			return 0, fmt.Errorf("expects at least 1 argument")
+		}
+		v := args[0]
+		for _, a := range args[1:] {
+			v = f(v, a)
+		}
+		return v, nil
+	}
+}
+
+func truth(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Eval evaluates the expression in env.
+func Eval(n *Node, env Env) (float64, error) {
+	switch n.Kind {
+	case Number:
+		return n.Value, nil
+	case Variable:
+		if v, ok := env.Vars[n.Name]; ok {
+			return v, nil
+		}
+		return 0, Error{fmt.Sprintf("Undefined variable %s", n.Name), n.Span}
+	case Call:
+		f, ok := env.Funcs[n.Name]
+		if !ok {
+			if f, ok = Functions[n.Name]; !ok {
+				return 0, Error{fmt.Sprintf("Undefined function %s", n.Name), n.Span}
+			}
+		}
+		args := make([]float64, len(n.Args))
+		for i, a := range n.Args {
+			v, err := Eval(a, env)
+			if err != nil {
+				return 0, err
+			}
+			args[i] = v
+		}
+		v, err := f(args...)
+		if err != nil {
+			return 0, Error{fmt.Sprintf("%s %s", n.Name, err), n.Span}
+		}
+		return v, nil
+	case Unary:
+		x, err := Eval(n.Args[0], env)
+		if err != nil {
+			return 0, err
+		}
+		switch n.Name {
+		case "-":
+			return -x, nil
+		case "!":
+			return truth(x == 0), nil
+		}
+		return x, nil
+	}
+
+	x, err := Eval(n.Args[0], env)
+	if err != nil {
+		return 0, err
+	}
+	// && and || short-circuit.
+	switch {
+	case n.Name == "&&" && x == 0:
+		return 0, nil
+	case n.Name == "||" && x != 0:
+		return 1, nil
+	}
+	y, err := Eval(n.Args[1], env)
+	if err != nil {
+		return 0, err
+	}
+	switch n.Name {
+	case "^":
+		return math.Pow(x, y), nil
+	case "*":
+		return x * y, nil
+	case "/", "%":
+		if y == 0 {
+			return 0, Error{"Division by zero", n.Span}
+		}
+		if n.Name == "%" {
+			return math.Mod(x, y), nil
+		}
+		return x / y, nil
+	case "+":
+		return x + y, nil
+	case "-":
+		return x - y, nil
+	case "<":
+		return truth(x < y), nil
+	case "<=":
+		return truth(x <= y), nil
+	case ">":
+		return truth(x > y), nil
+	case ">=":
+		return truth(x >= y), nil
+	case "==":
+		return truth(x == y), nil
+	case "!=":
+		return truth(x != y), nil
+	}
+	return truth(y != 0), nil
+}
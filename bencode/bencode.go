@@ -0,0 +1,149 @@
+// Package bencode parses bencoded data, the serialization used by
+// BitTorrent. Integers decode to int64, byte strings to string,
+// lists to []interface{} and dictionaries to map[string]interface{}.
+//
+// Parsing is strict, so that a decoded value always has exactly one
+// encoding: integers and lengths must not have leading zeros, and
+// dictionary keys must be unique and sorted as raw byte strings.
+package bencode
+
+import (
+	"strconv"
+
+	"parsec"
+)
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// digits consumes a run of decimal digits and returns them, failing on
+// leading zeros.
+func digits(st *parsec.ParseState, what string) (string, error) {
+	start, begin := st.Position(), st.Pos
+	for st.Pos < len(st.Source) && isDigit(st.Source[st.Pos]) {
+		st.Pos++
+	}
+	s := st.Source[begin:st.Pos]
+	if s == "" {
+		return "", st.ErrorAt(start, "Expected %s", what)
+	}
+	if len(s) > 1 && s[0] == '0' {
+		return "", st.ErrorAt(start, "Leading zero in %s", what)
+	}
+	return s, nil
+}
+
+// ByteString parses a byte string such as 4:spam and returns it as a
+// string sharing the input's memory.
+func ByteString(st *parsec.ParseState) (interface{}, error) {
+	start := st.Position()
+	if st.Pos >= len(st.Source) || !isDigit(st.Source[st.Pos]) {
+		return parsec.Fail("Expected byte string")(st)
+	}
+	s, err := digits(st, "string length")
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n > len(st.Source)-st.Pos-1 {
+		return nil, st.ErrorAt(start, "String length %s exceeds remaining input", s)
+	}
+	if _, err := parsec.Char(':')(st); err != nil {
+		return nil, err
+	}
+	b := st.Source[st.Pos : st.Pos+n]
+	st.Pos += n
+	return b, nil
+}
+
+// Integer parses an integer such as i-42e and returns it as an int64.
+func Integer(st *parsec.ParseState) (interface{}, error) {
+	start := st.Position()
+	if _, err := parsec.Char('i')(st); err != nil {
+		return parsec.Fail("Expected integer")(st)
+	}
+	_, err := parsec.Char('-')(st)
+	negative := err == nil
+	s, err := digits(st, "integer")
+	if err != nil {
+		return nil, err
+	}
+	if negative && s == "0" {
+		return nil, st.ErrorAt(start, "Negative zero")
+	}
+	if _, err := parsec.Char('e')(st); err != nil {
+		return nil, err
+	}
+	if negative {
+		s = "-" + s
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return nil, st.ErrorAt(start, "Integer %s out of range", s)
+	}
+	return n, nil
+}
+
+var value parsec.Parser
+
+var valueRef = parsec.Parser(func(st *parsec.ParseState) (interface{}, error) {
+	return value(st)
+})
+
+var end = parsec.Char('e')
+
+var list = parsec.Char('l').Then(parsec.ManyTill(valueRef, end))
+
+// dict parses a dictionary, checking that its keys are in strictly
+// increasing order.
+func dict(st *parsec.ParseState) (interface{}, error) {
+	if _, err := parsec.Char('d')(st); err != nil {
+		return parsec.Fail("Expected dictionary")(st)
+	}
+	d := make(map[string]interface{})
+	prev, first := "", true
+	for {
+		if _, err := end(st); err == nil {
+			return d, nil
+		}
+		start := st.Position()
+		k, err := parsec.Parser(ByteString).Label("dictionary key")(st)
+		if err != nil {
+			return nil, err
+		}
+		key := k.(string)
+		switch {
+		case !first && key == prev:
+			return nil, st.ErrorAt(start, "Duplicate dictionary key %q", key)
+		case !first && key < prev:
+			return nil, st.ErrorAt(start, "Dictionary key %q out of order after %q", key, prev)
+		}
+		v, err := valueRef(st)
+		if err != nil {
+			return nil, err
+		}
+		d[key] = v
+		prev, first = key, false
+	}
+}
+
+func init() {
+	value = parsec.Parser(Integer).
+		Or(ByteString).
+		Or(list).
+		Or(dict).
+		Label("value")
+}
+
+// Value parses a single bencoded value.
+var Value = valueRef
+
+var document = Value.Bind(func(x interface{}) parsec.Parser {
+	return parsec.Parser(parsec.Eof).Then(parsec.Return(x))
+})
+
+// Parse parses data holding exactly one bencoded value.
+func Parse(data string) (interface{}, error) {
+	return document.Parse(data)
+}
@@ -0,0 +1,81 @@
+package parsec
+
+import (
+	"math/big"
+)
+
+// BigInteger parses an optionally signed decimal integer of any size and
+// returns it as a *big.Int.
+func BigInteger(st *ParseState) (interface{}, error) {
+	return bigInteger(st, NumberOptions{})
+}
+
+func BigIntegerWith(opts NumberOptions) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		return bigInteger(st, opts)
+	}
+}
+
+func bigInteger(st *ParseState, opts NumberOptions) (interface{}, error) {
+	clean, err := st.scanInteger(opts)
+	if err != nil {
+		return nil, err
+	}
+	v, _ := new(big.Int).SetString(clean, 10)
+	return v, nil
+}
+
+// BigRat parses a number in the syntax accepted by Float and returns its
+// exact value as a *big.Rat.
+func BigRat(st *ParseState) (interface{}, error) {
+	return bigRat(st, NumberOptions{})
+}
+
+func BigRatWith(opts NumberOptions) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		return bigRat(st, opts)
+	}
+}
+
+func bigRat(st *ParseState, opts NumberOptions) (interface{}, error) {
+	start, begin := st.Position(), st.Pos
+	clean, err := st.scanFloat(opts)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := new(big.Rat).SetString(clean)
+	if !ok {
+		return nil, st.trapAt(start, "Number %s out of range", st.Source[begin:st.Pos])
+	}
+	return v, nil
+}
+
+// BigFloat parses a number in the syntax accepted by Float and returns it
+// as a *big.Float with 64 bits of precision. Use BigFloatWith to choose
+// another precision.
+func BigFloat(st *ParseState) (interface{}, error) {
+	return bigFloat(st, NumberOptions{})
+}
+
+func BigFloatWith(opts NumberOptions) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		return bigFloat(st, opts)
+	}
+}
+
+func bigFloat(st *ParseState, opts NumberOptions) (interface{}, error) {
+	start, begin := st.Position(), st.Pos
+	clean, err := st.scanFloat(opts)
+	if err != nil {
+		return nil, err
+	}
+	prec := opts.Precision
+	if prec == 0 {
+		prec = 64
+	}
+	v, _, err := big.ParseFloat(clean, 10, prec, big.ToNearestEven)
+	if err != nil {
+		return nil, st.trapAt(start, "Number %s out of range", st.Source[begin:st.Pos])
+	}
+	return v, nil
+}
@@ -0,0 +1,341 @@
+package parsec
+
+import (
+	"fmt"
+	"hash/crc32"
+	"math"
+	"sort"
+	"strings"
+)
+
+func bigEndian(s string) uint64 {
+	var v uint64
+	for i := 0; i < len(s); i++ {
+		v = v<<8 | uint64(s[i])
+	}
+	return v
+}
+
+func littleEndian(s string) uint64 {
+	var v uint64
+	for i := len(s) - 1; i >= 0; i-- {
+		v = v<<8 | uint64(s[i])
+	}
+	return v
+}
+
+// fixedWidth returns a parser reading an n-byte value with order and
+// converting it with conv.
+func fixedWidth(n int, name string, order func(string) uint64, conv func(uint64) interface{}) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		s, ok := st.take(n)
+		if !ok {
+			return nil, st.trap("Expected %d bytes for %s but got %d", n, name, len(st.Source)-st.Pos)
+		}
+		return conv(order(s)), nil
+	}
+}
+
+// The binary primitives read fixed-width integers and IEEE 754 floats from
+// binary input and return the Go type they are named after. The BE and LE
+// variants are big- and little-endian.
+var (
+	Uint8 = fixedWidth(1, "uint8", bigEndian, func(v uint64) interface{} { return uint8(v) })
+	Int8  = fixedWidth(1, "int8", bigEndian, func(v uint64) interface{} { return int8(v) })
+
+	Uint16BE = fixedWidth(2, "uint16", bigEndian, func(v uint64) interface{} { return uint16(v) })
+	Uint16LE = fixedWidth(2, "uint16", littleEndian, func(v uint64) interface{} { return uint16(v) })
+	Uint32BE = fixedWidth(4, "uint32", bigEndian, func(v uint64) interface{} { return uint32(v) })
+	Uint32LE = fixedWidth(4, "uint32", littleEndian, func(v uint64) interface{} { return uint32(v) })
+	Uint64BE = fixedWidth(8, "uint64", bigEndian, func(v uint64) interface{} { return v })
+	Uint64LE = fixedWidth(8, "uint64", littleEndian, func(v uint64) interface{} { return v })
+
+	Int16BE = fixedWidth(2, "int16", bigEndian, func(v uint64) interface{} { return int16(v) })
+	Int16LE = fixedWidth(2, "int16", littleEndian, func(v uint64) interface{} { return int16(v) })
+	Int32BE = fixedWidth(4, "int32", bigEndian, func(v uint64) interface{} { return int32(v) })
+	Int32LE = fixedWidth(4, "int32", littleEndian, func(v uint64) interface{} { return int32(v) })
+	Int64BE = fixedWidth(8, "int64", bigEndian, func(v uint64) interface{} { return int64(v) })
+	Int64LE = fixedWidth(8, "int64", littleEndian, func(v uint64) interface{} { return int64(v) })
+
+	Float32BE = fixedWidth(4, "float32", bigEndian, func(v uint64) interface{} { return math.Float32frombits(uint32(v)) })
+	Float32LE = fixedWidth(4, "float32", littleEndian, func(v uint64) interface{} { return math.Float32frombits(uint32(v)) })
+	Float64BE = fixedWidth(8, "float64", bigEndian, func(v uint64) interface{} { return math.Float64frombits(v) })
+	Float64LE = fixedWidth(8, "float64", littleEndian, func(v uint64) interface{} { return math.Float64frombits(v) })
+)
+
+// The un-suffixed binary primitives use the byte order of the parse, which
+// is big-endian unless changed by WithLittleEndian, LittleEndian or
+// BigEndian.
+var (
+	Uint16  = ordered(Uint16BE, Uint16LE)
+	Uint32  = ordered(Uint32BE, Uint32LE)
+	Uint64  = ordered(Uint64BE, Uint64LE)
+	Int16   = ordered(Int16BE, Int16LE)
+	Int32   = ordered(Int32BE, Int32LE)
+	Int64   = ordered(Int64BE, Int64LE)
+	Float32 = ordered(Float32BE, Float32LE)
+	Float64 = ordered(Float64BE, Float64LE)
+)
+
+func ordered(be, le Parser) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		if st.littleEndian {
+			return le(st)
+		}
+		return be(st)
+	}
+}
+
+// WithLittleEndian makes the un-suffixed binary primitives little-endian
+// for the whole parse.
+func WithLittleEndian() ParseOption {
+	return func(st *ParseState) {
+		st.littleEndian = true
+	}
+}
+
+// LittleEndian runs p with the un-suffixed binary primitives
+// little-endian.
+func LittleEndian(p Parser) Parser {
+	return withByteOrder(p, true)
+}
+
+// BigEndian runs p with the un-suffixed binary primitives big-endian.
+func BigEndian(p Parser) Parser {
+	return withByteOrder(p, false)
+}
+
+func withByteOrder(p Parser, little bool) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		saved := st.littleEndian
+		st.littleEndian = little
+		defer func() { st.littleEndian = saved }()
+		return p(st)
+	}
+}
+
+// Bytes parses exactly n bytes and returns a copy of them as a []byte.
+func Bytes(n int) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		s, ok := st.take(n)
+		if !ok {
+			return nil, st.trap("Expected %d bytes but got %d", n, len(st.Source)-st.Pos)
+		}
+		return []byte(s), nil
+	}
+}
+
+// LengthPrefixed parses a length with length, then the number of bytes it
+// gives with the parser body returns for that length, failing unless the
+// body consumes exactly those bytes. The body cannot read past them, and
+// sees them in place, so its positions are relative to the whole input.
+// The length may be any integer type, as returned by the binary
+// primitives and Integer.
+func LengthPrefixed(length Parser, body func(n int) Parser) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		start := st.Position()
+		x, err := length(st)
+		if err != nil {
+			return nil, err
+		}
+		n, ok := toLength(x)
+		if !ok {
+			return nil, st.trapAt(start, "Invalid length %v", x)
+		}
+		if n > len(st.Source)-st.Pos {
+			st.sawEnd = true
+			return nil, st.trapAt(start, "Length %d exceeds remaining input", n)
+		}
+		source, sawEnd, end := st.Source, st.sawEnd, st.Pos+n
+		st.Source = source[:end]
+		x, err = body(n)(st)
+		if err == nil && st.Pos < end {
+			err = st.trap("Length-prefixed body used %d of %d bytes", n-(end-st.Pos), n)
+		}
+		// Reaching the end of the body is not reaching the end of input.
+		st.Source, st.sawEnd = source, sawEnd
+		if err != nil {
+			return nil, err
+		}
+		return x, nil
+	}
+}
+
+func toLength(x interface{}) (int, bool) {
+	var n int64
+	switch x := x.(type) {
+	case int:
+		n = int64(x)
+	case int8:
+		n = int64(x)
+	case int16:
+		n = int64(x)
+	case int32:
+		n = int64(x)
+	case int64:
+		n = x
+	case uint8:
+		n = int64(x)
+	case uint16:
+		n = int64(x)
+	case uint32:
+		n = int64(x)
+	case uint64:
+		if x > math.MaxInt32 {
+			return 0, false
+		}
+		n = int64(x)
+	default:
+		return 0, false
+	}
+	if n < 0 || n > math.MaxInt32 {
+		return 0, false
+	}
+	return int(n), true
+}
+
+// CString parses bytes up to a NUL terminator, consumes the terminator,
+// and returns the bytes before it as a string.
+var CString = CStringMax(-1)
+
+// CStringMax is CString failing if no terminator follows within max bytes.
+// A negative max means no limit.
+func CStringMax(max int) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		rest := st.Source[st.Pos:]
+		if max >= 0 && len(rest) > max {
+			rest = rest[:max+1]
+		}
+		n := strings.IndexByte(rest, 0)
+		if n < 0 {
+			if max >= 0 && len(rest) > max {
+				return nil, st.trap("Expected NUL terminator within %d bytes", max)
+			}
+			st.sawEnd = true
+			return nil, st.trap("Unterminated string: expected NUL terminator")
+		}
+		s := st.Source[st.Pos : st.Pos+n]
+		st.advance(n + 1)
+		return s, nil
+	}
+}
+
+// Checksummed parses region and then checksum, and passes the bytes region
+// consumed and the checksum's result to verify. An error from verify fails
+// the parse at the checksum; otherwise region's result is returned.
+func Checksummed(region, checksum Parser, verify func(data []byte, sum interface{}) error) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		begin := st.Pos
+		x, err := region(st)
+		if err != nil {
+			return nil, err
+		}
+		data := []byte(st.Source[begin:st.Pos])
+		pos := st.Position()
+		sum, err := checksum(st)
+		if err != nil {
+			return nil, err
+		}
+		if err := verify(data, sum); err != nil {
+			return nil, st.trapAt(pos, "%s", err)
+		}
+		return x, nil
+	}
+}
+
+// VerifyCRC32 is a verify function for Checksummed comparing the IEEE
+// CRC-32 of data with a uint32 checksum, as read by Uint32BE or Uint32LE.
+func VerifyCRC32(data []byte, sum interface{}) error {
+	if crc := crc32.ChecksumIEEE(data); crc != sum.(uint32) {
+		return fmt.Errorf("Checksum mismatch: expected %08x but data has %08x", sum, crc)
+	}
+	return nil
+}
+
+// MarkOrigin runs p with the origin used by AlignTo set to the current
+// position, for containers whose alignment is relative to their own start.
+// Outside MarkOrigin, the origin is the start of the input.
+func MarkOrigin(p Parser) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		saved := st.alignOrigin
+		st.alignOrigin = st.Pos
+		defer func() { st.alignOrigin = saved }()
+		return p(st)
+	}
+}
+
+// AlignTo skips whatever bytes lie before the next multiple of n bytes from
+// the origin, finishing a partly read byte first.
+func AlignTo(n int) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		AlignByte(st)
+		skip := (n - (st.Pos-st.alignOrigin)%n) % n
+		if _, ok := st.take(skip); !ok {
+			return nil, st.trap("Expected %d bytes of alignment but got %d", skip, len(st.Source)-st.Pos)
+		}
+		return nil, nil
+	}
+}
+
+// Padding parses n bytes that must all equal fill.
+func Padding(n int, fill byte) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		for i := 0; i < n; i++ {
+			if c, ok := st.next(func(c byte) bool { return c == fill }); !ok {
+				if st.Pos >= len(st.Source) {
+					return nil, st.trap("Expected %d more padding bytes", n-i)
+				}
+				return nil, st.trap("Expected padding byte 0x%02x but got 0x%02x", fill, c)
+			}
+		}
+		return nil, nil
+	}
+}
+
+// Magic parses the exact byte signature sig, such as "\x89PNG", and returns
+// it. It fails without consuming input if the signature does not match.
+func Magic(sig []byte) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		rest := st.Source[st.Pos:]
+		if strings.HasPrefix(rest, string(sig)) {
+			st.advance(len(sig))
+			return sig, nil
+		}
+		got := rest[:min(len(rest), len(sig))]
+		if strings.HasPrefix(string(sig), got) {
+			st.sawEnd = true
+		}
+		return nil, st.trap("Bad magic: expected % x but got % x", sig, got)
+	}
+}
+
+// DetectFormat returns a parser that chooses among formats by their
+// signatures, given as the keys of formats, and runs the parser of the
+// longest one matching the input. The chosen parser starts at the
+// signature, which it is expected to parse itself, as with Magic.
+func DetectFormat(formats map[string]Parser) Parser {
+	sigs := make([]string, 0, len(formats))
+	longest := 0
+	for sig := range formats {
+		sigs = append(sigs, sig)
+		longest = max(longest, len(sig))
+	}
+	sort.Slice(sigs, func(i, j int) bool {
+		if len(sigs[i]) != len(sigs[j]) {
+			return len(sigs[i]) > len(sigs[j])
+		}
+		return sigs[i] < sigs[j]
+	})
+	return func(st *ParseState) (interface{}, error) {
+		rest := st.Source[st.Pos:]
+		for _, sig := range sigs {
+			if strings.HasPrefix(rest, sig) {
+				return formats[sig](st)
+			}
+			if strings.HasPrefix(sig, rest) {
+				st.sawEnd = true
+			}
+		}
+		return nil, st.trap("Unknown format starting with % x", rest[:min(len(rest), longest)])
+	}
+}
@@ -0,0 +1,46 @@
+package parsec
+
+// Bits parses the next n bits of binary input, most significant bit
+// first, and returns them as a uint64. n may be at most 64. Bits may start
+// and end within a byte; byte-level parsers ignore a partly read byte, so
+// AlignByte must be used before returning to them.
+func Bits(n int) Parser {
+	if n < 0 || n > 64 {
+		panic("parsec: Bits count out of range")
+	}
+	return func(st *ParseState) (interface{}, error) {
+		if avail := (len(st.Source)-st.Pos)*8 - st.bit; avail < n {
+			st.sawEnd = true
+			return nil, st.trap("Expected %d bits but got %d", n, avail)
+		}
+		var v uint64
+		for i := 0; i < n; i++ {
+			b := st.Source[st.Pos] >> (7 - st.bit) & 1
+			v = v<<1 | uint64(b)
+			if st.bit++; st.bit == 8 {
+				st.Pos, st.bit = st.Pos+1, 0
+			}
+		}
+		return v, nil
+	}
+}
+
+var bit = Bits(1)
+
+// Flag parses a single bit and returns it as a bool.
+func Flag(st *ParseState) (interface{}, error) {
+	x, err := bit(st)
+	if err != nil {
+		return nil, err
+	}
+	return x.(uint64) == 1, nil
+}
+
+// AlignByte skips the rest of a partly read byte, if any, so that parsing
+// continues at a byte boundary.
+func AlignByte(st *ParseState) (interface{}, error) {
+	if st.bit != 0 {
+		st.Pos, st.bit = st.Pos+1, 0
+	}
+	return nil, nil
+}
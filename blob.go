@@ -0,0 +1,118 @@
+package parsec
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+// BlobOptions configures HexBytesWith and Base64BytesWith.
+type BlobOptions struct {
+	// Spaces permits spaces, tabs and newlines within the encoded run.
+	Spaces bool
+	// URL selects the URL-safe base64 alphabet, with '-' and '_'.
+	URL bool
+}
+
+func isBlobSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// scanBlob consumes a run of bytes satisfying alphabet, and whitespace if
+// permitted, and returns the run without whitespace along with the source
+// offset of each byte kept.
+func (st *ParseState) scanBlob(alphabet func(byte) bool, spaces bool) (string, []int) {
+	var clean []byte
+	var offsets []int
+	end, line := st.Pos, st.Line
+	for !st.atEnd(st.Pos) {
+		c := st.Source[st.Pos]
+		if !alphabet(c) && (!spaces || !isBlobSpace(c)) {
+			break
+		}
+		st.advance(1)
+		if alphabet(c) {
+			clean = append(clean, c)
+			offsets = append(offsets, st.Pos-1)
+			end, line = st.Pos, st.Line
+		}
+	}
+	// Trailing whitespace is not part of the blob.
+	st.Pos, st.Line = end, line
+	return string(clean), offsets
+}
+
+// trapAtOffset reports an error at an earlier offset in the source, which
+// must not precede mark.
+func (st *ParseState) trapAtOffset(mark Position, begin, offset int, format string, args ...interface{}) ParseErr {
+	pos, line := st.Pos, st.Line
+	st.Pos, st.Line = begin, mark.Line
+	st.advance(offset - begin)
+	err := st.trap(format, args...)
+	st.Pos, st.Line = pos, line
+	return err
+}
+
+// HexBytes parses a run of hex digits and returns the bytes they encode.
+func HexBytes(st *ParseState) (interface{}, error) {
+	return hexBytes(st, BlobOptions{})
+}
+
+func HexBytesWith(opts BlobOptions) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		return hexBytes(st, opts)
+	}
+}
+
+func hexBytes(st *ParseState, opts BlobOptions) (interface{}, error) {
+	start, begin := st.Position(), st.Pos
+	digits, offsets := st.scanBlob(func(c byte) bool { return hexValue(c) >= 0 }, opts.Spaces)
+	if len(digits) == 0 {
+		return nil, st.trap("Expected hex digits")
+	}
+	if len(digits)%2 != 0 {
+		return nil, st.trapAtOffset(start, begin, offsets[len(offsets)-1], "Odd number of hex digits")
+	}
+	data := make([]byte, len(digits)/2)
+	for i := range data {
+		data[i] = byte(hexValue(digits[2*i])<<4 | hexValue(digits[2*i+1]))
+	}
+	return data, nil
+}
+
+// Base64Bytes parses a run of standard base64, with or without padding,
+// and returns the decoded bytes.
+func Base64Bytes(st *ParseState) (interface{}, error) {
+	return base64Bytes(st, BlobOptions{})
+}
+
+func Base64BytesWith(opts BlobOptions) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		return base64Bytes(st, opts)
+	}
+}
+
+func base64Bytes(st *ParseState, opts BlobOptions) (interface{}, error) {
+	extra, enc := "+/", base64.StdEncoding
+	if opts.URL {
+		extra, enc = "-_", base64.URLEncoding
+	}
+	alphabet := func(c byte) bool { return isDigit(c) || isLetter(c) || c == extra[0] || c == extra[1] }
+	start, begin := st.Position(), st.Pos
+	text, offsets := st.scanBlob(func(c byte) bool { return alphabet(c) || c == '=' }, opts.Spaces)
+	if len(text) == 0 || text[0] == '=' {
+		st.Pos, st.Line = begin, start.Line
+		return nil, st.trap("Expected base64 data")
+	}
+	if !strings.Contains(text, "=") {
+		enc = enc.WithPadding(base64.NoPadding)
+	}
+	data, err := enc.DecodeString(text)
+	if err != nil {
+		offset := st.Pos
+		if n, ok := err.(base64.CorruptInputError); ok && int(n) < len(offsets) {
+			offset = offsets[n]
+		}
+		return nil, st.trapAtOffset(start, begin, offset, "Invalid base64 data")
+	}
+	return data, nil
+}
@@ -0,0 +1,28 @@
+package parsec
+
+// Checkpoint is the state of a Feeder between values, from which a Feeder
+// can be restored, such as after a restart of a long ingestion job. The
+// input not yet consumed is Pending, so reading resumes at the stream
+// offset Offset+len(Pending). Checkpoints marshal to JSON; to get User back
+// as its own type rather than as generic JSON values, set User to a
+// pointer to a value of that type before unmarshaling.
+type Checkpoint struct {
+	Offset  int         `json:"offset"`
+	Line    int         `json:"line"`
+	Pending []byte      `json:"pending,omitempty"`
+	User    interface{} `json:"user,omitempty"`
+}
+
+// Checkpoint returns the Feeder's state. It does not include an error
+// returned by Next, or the end of input marked by Close.
+func (f *Feeder) Checkpoint() Checkpoint {
+	return Checkpoint{Offset: f.offset, Line: f.line, Pending: append([]byte(nil), f.buf...), User: f.User}
+}
+
+// RestoreFeeder returns a Feeder running p from the state in cp.
+func RestoreFeeder(p Parser, cp Checkpoint) *Feeder {
+	f := NewFeeder(p)
+	f.offset, f.line, f.User = cp.Offset, cp.Line, cp.User
+	f.Append(cp.Pending)
+	return f
+}
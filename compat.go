@@ -0,0 +1,131 @@
+package parsec
+
+import (
+	"fmt"
+	"sort"
+)
+
+// RuleChange is a difference in a rule's structure between two versions
+// of a Grammar. Breaking changes are those that may remove inputs the old
+// version accepted: a removed rule, literal or reference, or fewer
+// alternatives.
+type RuleChange struct {
+	Rule     string
+	Change   string
+	Breaking bool
+}
+
+// CompareRules compares the rules of two versions of a grammar, before
+// and after a change, by what their RuleInfo records, and reports the
+// rules whose language changed, breaking changes first. Only the parts of
+// a rule built with Lit, Choice and Ref are compared: a change to another
+// parser of the rule goes unnoticed, which CompareGrammars can catch with
+// sample inputs.
+func CompareRules(before, after *Grammar) []RuleChange {
+	var changes []RuleChange
+	add := func(rule string, breaking bool, format string, args ...interface{}) {
+		changes = append(changes, RuleChange{Rule: rule, Change: fmt.Sprintf(format, args...), Breaking: breaking})
+	}
+	for name, old := range before.info {
+		info, ok := after.info[name]
+		if !ok {
+			add(name, true, "rule removed")
+			continue
+		}
+		for _, s := range setDiff(old.Literals, info.Literals) {
+			add(name, true, "literal %q removed", s)
+		}
+		for _, s := range setDiff(info.Literals, old.Literals) {
+			add(name, false, "literal %q added", s)
+		}
+		for _, s := range setDiff(old.Refs, info.Refs) {
+			add(name, true, "reference to %s removed", s)
+		}
+		for _, s := range setDiff(info.Refs, old.Refs) {
+			add(name, false, "reference to %s added", s)
+		}
+		if old.Alternatives != info.Alternatives {
+			add(name, info.Alternatives < old.Alternatives, "alternatives changed from %d to %d", old.Alternatives, info.Alternatives)
+		}
+	}
+	for name := range after.info {
+		if _, ok := before.info[name]; !ok {
+			add(name, false, "rule added")
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.Breaking != b.Breaking {
+			return a.Breaking
+		}
+		if a.Rule != b.Rule {
+			return a.Rule < b.Rule
+		}
+		return a.Change < b.Change
+	})
+	return changes
+}
+
+// setDiff returns the strings of the sorted set a missing from b.
+func setDiff(a, b []string) []string {
+	var out []string
+	for _, s := range a {
+		if i := sort.SearchStrings(b, s); i == len(b) || b[i] != s {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// LanguageChange is an input that one version of a rule accepts and the
+// other rejects. Breaking changes are inputs the old version accepted.
+type LanguageChange struct {
+	Rule     string
+	Input    string
+	Breaking bool
+	// Err is the error of the version rejecting Input.
+	Err error
+}
+
+// CompareGrammars checks two versions of a grammar's rules, before and
+// after a change and keyed by rule name, against sample inputs for each
+// rule, and reports the inputs whose acceptance changed, breaking changes
+// first. A rule accepts an input if it parses all of it. It complements
+// CompareRules for rules built from parsers a Grammar cannot describe, and
+// is only as good as the samples: they should cover every alternative and
+// literal of the old rules, such as the inputs of their tests, plus any
+// inputs the new rules are meant to add. Rules missing from either version
+// are compared as rejecting everything.
+func CompareGrammars(before, after map[string]Parser, samples map[string][]string) []LanguageChange {
+	accepts := func(rules map[string]Parser, rule, input string) error {
+		p, ok := rules[rule]
+		if !ok {
+			return ParseErr{Reason: "Rule " + rule + " is not defined", Line: 1}
+		}
+		_, err := p.Then(Eof).Parse(input)
+		return err
+	}
+	var changes []LanguageChange
+	for rule, inputs := range samples {
+		for _, input := range inputs {
+			oldErr, newErr := accepts(before, rule, input), accepts(after, rule, input)
+			switch {
+			case oldErr == nil && newErr != nil:
+				changes = append(changes, LanguageChange{Rule: rule, Input: input, Breaking: true, Err: newErr})
+			case oldErr != nil && newErr == nil:
+				changes = append(changes, LanguageChange{Rule: rule, Input: input, Err: oldErr})
+			}
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.Breaking != b.Breaking {
+			return a.Breaking
+		}
+		if a.Rule != b.Rule {
+			return a.Rule < b.Rule
+		}
+		return a.Input < b.Input
+	})
+	return changes
+}
@@ -0,0 +1,249 @@
+// Package csv reads comma-separated values as specified by RFC 4180, and
+// other delimiter-separated formats described by a Dialect, recording the
+// position of every field.
+package csv
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"parsec"
+)
+
+type Field struct {
+	Value string
+	Span  parsec.Span
+}
+
+type Record []Field
+
+// Strings returns the values of the record's fields.
+func (r Record) Strings() []string {
+	values := make([]string, len(r))
+	for i, f := range r {
+		values[i] = f.Value
+	}
+	return values
+}
+
+// Dialect describes a delimiter-separated values format.
+type Dialect struct {
+	// Delimiter separates fields. Defaults to ','.
+	Delimiter byte
+	// Quote encloses fields that contain delimiters, quotes or line
+	// breaks, or 0 if fields cannot be quoted.
+	Quote byte
+	// Escape makes the following character literal, in quoted and
+	// unquoted fields. If 0, or equal to Quote, a quote inside a quoted
+	// field is written twice instead.
+	Escape byte
+	// Comment, if not empty, marks lines to skip when it starts them.
+	Comment string
+	// Header treats the first record as column names rather than data.
+	Header bool
+}
+
+var RFC4180 = Dialect{Delimiter: ',', Quote: '"'}
+var TSV = Dialect{Delimiter: '\t'}
+var Pipe = Dialect{Delimiter: '|', Quote: '"'}
+
+func (d Dialect) normalize() Dialect {
+	if d.Delimiter == 0 {
+		d.Delimiter = ','
+	}
+	if d.Escape == d.Quote {
+		d.Escape = 0
+	}
+	return d
+}
+
+// RecordParser returns a parser for one record of the dialect, including
+// its line terminator.
+func (d Dialect) RecordParser() parsec.Parser {
+	d = d.normalize()
+	special := []byte{d.Delimiter, '\r', '\n'}
+	if d.Quote != 0 {
+		special = append(special, d.Quote)
+	}
+	var escaped parsec.Parser
+	if d.Escape != 0 {
+		special = append(special, d.Escape)
+		escaped = parsec.Char(d.Escape).Then(parsec.AnyChar)
+	}
+	char := parsec.NoneOf(special)
+	if escaped != nil {
+		char = char.Or(escaped)
+	}
+	field := parsec.Many(char).ToString()
+	if d.Quote != 0 {
+		quote := string(d.Quote)
+		inner := parsec.NoneOf([]byte{d.Quote, d.Escape})
+		if escaped != nil {
+			inner = inner.Or(escaped)
+		} else {
+			inner = parsec.NoneOf([]byte{d.Quote}).Or(parsec.String(quote + quote).Then(parsec.Return(quote)))
+		}
+		quoted := parsec.Many(inner).ToString().
+			Between(parsec.Char(d.Quote), parsec.Char(d.Quote).Label("closing quote"))
+		field = quoted.Or(field)
+	}
+	field = field.WithSpan().Bind(func(x interface{}) parsec.Parser {
+		s := x.(parsec.Spanned)
+		return parsec.Return(Field{Value: s.Value.(string), Span: s.Span})
+	})
+	return field.SepBy1(parsec.Char(d.Delimiter)).Bind(func(x interface{}) parsec.Parser {
+		return parsec.Eol.Label("end of record").Then(parsec.Return(x))
+	}).Bind(func(x interface{}) parsec.Parser {
+		record := make(Record, len(x.([]interface{})))
+		for i, f := range x.([]interface{}) {
+			record[i] = f.(Field)
+		}
+		return parsec.Return(record)
+	})
+}
+
+// RecordParser parses one RFC 4180 record, including its line terminator.
+var RecordParser = RFC4180.RecordParser()
+
+// Reader reads records one at a time from an io.Reader, so that large
+// inputs need not be held in memory.
+type Reader struct {
+	dialect Dialect
+	record  parsec.Parser
+	r       *bufio.Reader
+	offset  int
+	line    int
+	header  Record
+}
+
+// NewReader returns a Reader for RFC 4180 input.
+func NewReader(r io.Reader) *Reader {
+	return NewDialectReader(r, RFC4180)
+}
+
+func NewDialectReader(r io.Reader, d Dialect) *Reader {
+	d = d.normalize()
+	return &Reader{dialect: d, record: d.RecordParser(), r: bufio.NewReader(r), line: 1}
+}
+
+// next returns the text of the next record: enough lines to close any
+// quoted field or escaped line break that spans lines. Comment lines are
+// returned on their own.
+func (r *Reader) next() (string, error) {
+	var sb strings.Builder
+	quoted := false
+	for {
+		line, err := r.r.ReadString('\n')
+		comment := sb.Len() == 0 && r.dialect.Comment != "" && strings.HasPrefix(line, r.dialect.Comment)
+		sb.WriteString(line)
+		if err != nil {
+			if err == io.EOF && sb.Len() > 0 {
+				err = nil
+			}
+			return sb.String(), err
+		}
+		if comment {
+			return line, nil
+		}
+		escaped := false
+		for i := 0; i < len(line); i++ {
+			switch c := line[i]; {
+			case c == r.dialect.Escape && c != 0:
+				escaped = i+1 < len(line) && line[i+1] == '\n' || i+2 < len(line) && line[i+1:] == "\r\n"
+				i++
+			case c == r.dialect.Quote && c != 0:
+				quoted = !quoted
+			}
+		}
+		if !quoted && !escaped {
+			return sb.String(), nil
+		}
+	}
+}
+
+// Read returns the next record, or io.EOF at the end of input. Empty lines
+// and comments are skipped. Positions in the record and in errors are
+// relative to the whole input.
+func (r *Reader) Read() (Record, error) {
+	if r.dialect.Header && r.header == nil {
+		header, err := r.read()
+		if err != nil {
+			return nil, err
+		}
+		r.header = header
+	}
+	return r.read()
+}
+
+// Header returns the header record of a dialect with Header set, reading it
+// if no record has been read yet.
+func (r *Reader) Header() (Record, error) {
+	if r.dialect.Header && r.header == nil {
+		header, err := r.read()
+		if err != nil {
+			return nil, err
+		}
+		r.header = header
+	}
+	return r.header, nil
+}
+
+func (r *Reader) read() (Record, error) {
+	for {
+		text, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+		if text == "\n" || text == "\r\n" || r.dialect.Comment != "" && strings.HasPrefix(text, r.dialect.Comment) {
+			r.offset += len(text)
+			r.line += strings.Count(text, "\n")
+			continue
+		}
+		st := parsec.ParseState{Source: text, Line: 1}
+		x, err := r.record(&st)
+		if err != nil {
+			if pe, ok := err.(parsec.ParseErr); ok {
+				pe.Offset += r.offset
+				pe.Line += r.line - 1
+				err = pe
+			}
+			// The bad record is consumed; the next starts after it.
+			r.offset += len(text)
+			r.line += strings.Count(text, "\n")
+			return nil, err
+		}
+		record := x.(Record)
+		for i := range record {
+			record[i].Span.Start = r.shift(record[i].Span.Start)
+			record[i].Span.End = r.shift(record[i].Span.End)
+		}
+		r.offset += len(text)
+		r.line += st.Line - 1
+		return record, nil
+	}
+}
+
+func (r *Reader) shift(pos parsec.Position) parsec.Position {
+	return parsec.Position{Offset: pos.Offset + r.offset, Line: pos.Line + r.line - 1}
+}
+
+// ReadAll reads all remaining records.
+func (r *Reader) ReadAll() ([]Record, error) {
+	var records []Record
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+		records = append(records, record)
+	}
+}
+
+// Parse parses all records in RFC 4180 source.
+func Parse(source string) ([]Record, error) {
+	return NewReader(strings.NewReader(source)).ReadAll()
+}
@@ -0,0 +1,17 @@
+package parsec
+
+// Cut returns a parser that behaves like p, except that a failure of p is
+// final: enclosing Try parsers do not rewind over it, and Either does not
+// try further alternatives even if no input was consumed. Placed after the
+// input that identifies a construct, as in Try(open).Then(Cut(rest)), it
+// keeps errors at the real problem instead of at the start of the
+// construct, and stops needless backtracking.
+func Cut(p Parser) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		x, err := p(st)
+		if err != nil {
+			st.cut = true
+		}
+		return x, err
+	}
+}
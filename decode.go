@@ -0,0 +1,207 @@
+package parsec
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type binaryTag struct {
+	littleEndian bool
+	length       int
+	lengthField  string
+	cstring      bool
+}
+
+func parseBinaryTag(tag string, inherited binaryTag) (binaryTag, error) {
+	t := binaryTag{littleEndian: inherited.littleEndian, length: -1}
+	for _, opt := range strings.Split(tag, ",") {
+		switch {
+		case opt == "":
+		case opt == "be":
+			t.littleEndian = false
+		case opt == "le":
+			t.littleEndian = true
+		case opt == "cstring":
+			t.cstring = true
+		case strings.HasPrefix(opt, "len="):
+			if n, err := strconv.Atoi(opt[4:]); err == nil && n >= 0 {
+				t.length = n
+			} else {
+				t.lengthField = opt[4:]
+			}
+		default:
+			return t, fmt.Errorf("parsec: unknown bin tag option %q", opt)
+		}
+	}
+	return t, nil
+}
+
+// DecodeBinary decodes data into the struct v points to, reading its
+// exported fields in order with the binary primitives. Integers, floats,
+// bools (one byte, true if not zero), arrays and nested structs are
+// decoded from their size in the type; strings, []byte and other slices
+// need a length. The bin struct tag holds comma-separated options:
+//
+//	be, le      byte order for the field and anything nested in it; the
+//	            default is the byte order of the parse, big-endian unless
+//	            opts include WithLittleEndian
+//	len=N       number of bytes in a string or elements in a slice
+//	len=Field   the same, taken from an integer field decoded earlier in
+//	            the same struct
+//	cstring     a NUL-terminated string, at most len bytes if given
+//
+// A field tagged bin:"-" is skipped. Strings of fixed length have trailing
+// NUL padding removed. Errors name the field and its offset in data, and
+// data left after the last field is ignored.
+func DecodeBinary(data []byte, v interface{}, opts ...ParseOption) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("parsec: DecodeBinary needs a pointer to a struct, not %T", v)
+	}
+	st := ParseState{Source: string(data), Line: 1}
+	for _, opt := range opts {
+		opt(&st)
+	}
+	return st.decodeStruct(rv.Elem(), binaryTag{littleEndian: st.littleEndian}, "")
+}
+
+func (st *ParseState) decodeStruct(v reflect.Value, tag binaryTag, path string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() || f.Tag.Get("bin") == "-" {
+			continue
+		}
+		ft, err := parseBinaryTag(f.Tag.Get("bin"), tag)
+		if err != nil {
+			return err
+		}
+		if ft.lengthField != "" {
+			lf := v.FieldByName(ft.lengthField)
+			if !lf.IsValid() || t.Field(i).Index[0] <= fieldIndex(t, ft.lengthField) {
+				return fmt.Errorf("parsec: length field %s of %s must precede it", ft.lengthField, f.Name)
+			}
+			n, ok := toLength(lf.Interface())
+			if !ok {
+				return st.trap("Field %s at offset %d: invalid length %v", path+f.Name, st.Pos, lf.Interface())
+			}
+			ft.length = n
+		}
+		if err := st.decodeValue(v.Field(i), ft, path+f.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func fieldIndex(t reflect.Type, name string) int {
+	f, _ := t.FieldByName(name)
+	return f.Index[0]
+}
+
+var binaryParsers = map[reflect.Kind][2]Parser{
+	reflect.Uint8:   {Uint8, Uint8},
+	reflect.Int8:    {Int8, Int8},
+	reflect.Uint16:  {Uint16BE, Uint16LE},
+	reflect.Int16:   {Int16BE, Int16LE},
+	reflect.Uint32:  {Uint32BE, Uint32LE},
+	reflect.Int32:   {Int32BE, Int32LE},
+	reflect.Uint64:  {Uint64BE, Uint64LE},
+	reflect.Int64:   {Int64BE, Int64LE},
+	reflect.Float32: {Float32BE, Float32LE},
+	reflect.Float64: {Float64BE, Float64LE},
+	reflect.Bool:    {Uint8, Uint8},
+}
+
+func (st *ParseState) decodeValue(v reflect.Value, tag binaryTag, path string) error {
+	offset := st.Pos
+	fail := func(err error) error {
+		if pe, ok := err.(ParseErr); ok {
+			pe.Reason = fmt.Sprintf("Field %s at offset %d: %s", path, offset, pe.Reason)
+			return pe
+		}
+		return err
+	}
+	run := func(p Parser) (interface{}, error) {
+		x, err := p(st)
+		if err != nil {
+			return nil, fail(err)
+		}
+		return x, nil
+	}
+	switch k := v.Kind(); k {
+	case reflect.Struct:
+		return st.decodeStruct(v, tag, path+".")
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := st.decodeValue(v.Index(i), tag, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.String:
+		var p Parser
+		switch {
+		case tag.cstring:
+			p = CStringMax(tag.length)
+		case tag.length >= 0:
+			p = Bytes(tag.length)
+		default:
+			return fmt.Errorf("parsec: string field %s needs a len or cstring option", path)
+		}
+		x, err := run(p)
+		if err != nil {
+			return err
+		}
+		if b, ok := x.([]byte); ok {
+			x = strings.TrimRight(string(b), "\x00")
+		}
+		v.SetString(x.(string))
+		return nil
+	case reflect.Slice:
+		if tag.length < 0 {
+			return fmt.Errorf("parsec: slice field %s needs a len option", path)
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			x, err := run(Bytes(tag.length))
+			if err != nil {
+				return err
+			}
+			v.SetBytes(x.([]byte))
+			return nil
+		}
+		// The length comes from the input, so the slice grows as elements
+		// decode rather than trusting it for the allocation.
+		v.Set(reflect.MakeSlice(v.Type(), 0, min(tag.length, len(st.Source)-st.Pos)))
+		zero := reflect.Zero(v.Type().Elem())
+		elem := binaryTag{littleEndian: tag.littleEndian, length: -1}
+		for i := 0; i < tag.length; i++ {
+			v.Set(reflect.Append(v, zero))
+			if err := st.decodeValue(v.Index(i), elem, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		ps, ok := binaryParsers[k]
+		if !ok {
+			return fmt.Errorf("parsec: cannot decode field %s of type %s", path, v.Type())
+		}
+		p := ps[0]
+		if tag.littleEndian {
+			p = ps[1]
+		}
+		x, err := run(p)
+		if err != nil {
+			return err
+		}
+		if k == reflect.Bool {
+			v.SetBool(x.(uint8) != 0)
+		} else {
+			v.Set(reflect.ValueOf(x).Convert(v.Type()))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,199 @@
+// Package der provides parsers for the tag-length-value encoding of ASN.1
+// DER, the format of X.509 certificates and related structures. TLV reads
+// any element without interpreting it; Nested parses the contents of a
+// constructed element with a further parser, so that structures can be
+// decoded field by field.
+package der
+
+import (
+	"fmt"
+
+	"parsec"
+)
+
+type Class int
+
+const (
+	Universal Class = iota
+	Application
+	ContextSpecific
+	Private
+)
+
+// Universal tag numbers.
+const (
+	TagBoolean         = 1
+	TagInteger         = 2
+	TagBitString       = 3
+	TagOctetString     = 4
+	TagNull            = 5
+	TagOID             = 6
+	TagUTF8String      = 12
+	TagSequence        = 16
+	TagSet             = 17
+	TagPrintableString = 19
+	TagIA5String       = 22
+	TagUTCTime         = 23
+	TagGeneralizedTime = 24
+)
+
+type Tag struct {
+	Class       Class
+	Constructed bool
+	Number      int
+}
+
+var Sequence = Tag{Class: Universal, Constructed: true, Number: TagSequence}
+var Set = Tag{Class: Universal, Constructed: true, Number: TagSet}
+
+func (t Tag) String() string {
+	form := "primitive"
+	if t.Constructed {
+		form = "constructed"
+	}
+	class := [...]string{"universal", "application", "context-specific", "private"}[t.Class]
+	return fmt.Sprintf("[%s %d %s]", class, t.Number, form)
+}
+
+// TLV is an element with its contents left encoded.
+type TLV struct {
+	Tag Tag
+	// Value holds the contents octets, sharing the input's memory.
+	Value string
+	Span  parsec.Span
+}
+
+func next(st *parsec.ParseState) (byte, bool) {
+	if st.Pos >= len(st.Source) {
+		return 0, false
+	}
+	c := st.Source[st.Pos]
+	st.Pos++
+	return c, true
+}
+
+// TagParser parses an identifier octet, followed by the base-128 tag
+// number in the high-tag-number form, and returns a Tag.
+func TagParser(st *parsec.ParseState) (interface{}, error) {
+	start := st.Position()
+	c, ok := next(st)
+	if !ok {
+		return parsec.Fail("Expected tag")(st)
+	}
+	t := Tag{Class: Class(c >> 6), Constructed: c&0x20 != 0, Number: int(c & 0x1f)}
+	if t.Number != 0x1f {
+		return t, nil
+	}
+	t.Number = 0
+	for i := 0; ; i++ {
+		c, ok := next(st)
+		if !ok {
+			return nil, st.ErrorAt(start, "Truncated tag")
+		}
+		if i == 0 && c == 0x80 {
+			return nil, st.ErrorAt(start, "Tag number not minimally encoded")
+		}
+		if t.Number > (1<<31-1)>>7 {
+			return nil, st.ErrorAt(start, "Tag number too large")
+		}
+		t.Number = t.Number<<7 | int(c&0x7f)
+		if c&0x80 == 0 {
+			break
+		}
+	}
+	if t.Number < 0x1f {
+		return nil, st.ErrorAt(start, "Tag number %d not minimally encoded", t.Number)
+	}
+	return t, nil
+}
+
+// Length parses a definite length in the short or long form and returns
+// it as an int. DER requires the shortest encoding and does not allow the
+// indefinite form. The length must fit in the remaining input.
+func Length(st *parsec.ParseState) (interface{}, error) {
+	start := st.Position()
+	c, ok := next(st)
+	switch {
+	case !ok:
+		return parsec.Fail("Expected length")(st)
+	case c == 0x80:
+		return nil, st.ErrorAt(start, "Indefinite length not allowed in DER")
+	case c == 0xff:
+		return nil, st.ErrorAt(start, "Reserved length octet 0xff")
+	}
+	n := int(c)
+	if c&0x80 != 0 {
+		size := int(c & 0x7f)
+		if size > 4 {
+			return nil, st.ErrorAt(start, "Length of %d octets too large", size)
+		}
+		n = 0
+		for i := 0; i < size; i++ {
+			b, ok := next(st)
+			if !ok {
+				return nil, st.ErrorAt(start, "Truncated length")
+			}
+			if i == 0 && b == 0 {
+				return nil, st.ErrorAt(start, "Length not minimally encoded")
+			}
+			n = n<<8 | int(b)
+		}
+		if n < 0x80 {
+			return nil, st.ErrorAt(start, "Length %d not minimally encoded", n)
+		}
+	}
+	if n > len(st.Source)-st.Pos {
+		return nil, st.ErrorAt(start, "Length %d exceeds remaining input", n)
+	}
+	return n, nil
+}
+
+// TLVParser parses an element and returns it as a TLV.
+func TLVParser(st *parsec.ParseState) (interface{}, error) {
+	start := st.Position()
+	t, err := TagParser(st)
+	if err != nil {
+		return nil, err
+	}
+	n, err := Length(st)
+	if err != nil {
+		return nil, err
+	}
+	v := st.Source[st.Pos : st.Pos+n.(int)]
+	st.Pos += n.(int)
+	return TLV{Tag: t.(Tag), Value: v, Span: parsec.Span{Start: start, End: st.Position()}}, nil
+}
+
+// Nested returns a parser for an element with the given tag whose
+// contents are parsed by p, which must consume all of them. p sees the
+// contents in place, so its positions are relative to the whole input.
+// If the next element has a different tag, Nested fails without consuming
+// input, so that optional and alternative elements can be parsed with Or.
+func Nested(tag Tag, p parsec.Parser) parsec.Parser {
+	return func(st *parsec.ParseState) (interface{}, error) {
+		start, pos := st.Position(), st.Pos
+		t, err := TagParser(st)
+		if err != nil {
+			return nil, err
+		}
+		if t.(Tag) != tag {
+			st.Pos = pos
+			return nil, st.ErrorAt(start, "Expected %s but got %s", tag, t.(Tag))
+		}
+		n, err := Length(st)
+		if err != nil {
+			return nil, err
+		}
+		source, end := st.Source, st.Pos+n.(int)
+		st.Source = source[:end]
+		x, err := p(st)
+		if err == nil && st.Pos < end {
+			err = st.ErrorAt(st.Position(), "Unexpected data after contents of %s", tag)
+		}
+		st.Source = source
+		if err != nil {
+			return nil, err
+		}
+		return x, nil
+	}
+}
@@ -0,0 +1,88 @@
+package parsec
+
+import (
+	"strings"
+)
+
+type EmailAddress struct {
+	Local  string // as written, including quotes for a quoted local part
+	Domain string // as written, including brackets for a domain literal
+}
+
+func (a EmailAddress) String() string {
+	return a.Local + "@" + a.Domain
+}
+
+func isAtext(c byte) bool {
+	return isDigit(c) || isLetter(c) || strings.IndexByte("!#$%&'*+-/=?^_`{|}~", c) >= 0
+}
+
+// dotAtom consumes atoms separated by single dots.
+func (st *ParseState) dotAtom(what string) (string, error) {
+	begin := st.Pos
+	for {
+		if st.skipWhile(isAtext) == 0 {
+			return "", st.trap("Expected %s", what)
+		}
+		rest := st.peek(2)
+		if len(rest) > 1 && rest[0] == '.' && rest[1] == '.' {
+			st.Pos++
+			return "", st.trap("Unexpected '.' in %s", what)
+		}
+		// A trailing dot is left alone, as it usually ends a sentence.
+		if len(rest) < 2 || rest[0] != '.' || !isAtext(rest[1]) {
+			return st.Source[begin:st.Pos], nil
+		}
+		st.Pos++
+	}
+}
+
+// Email parses an RFC 5322 addr-spec: a dot-atom or quoted-string local
+// part, '@', and a dot-atom domain or bracketed domain literal. It does
+// not accept comments or folding whitespace.
+func Email(st *ParseState) (interface{}, error) {
+	var addr EmailAddress
+	begin := st.Pos
+	if _, ok := st.next(func(c byte) bool { return c == '"' }); ok {
+		for {
+			c, ok := st.next(func(c byte) bool { return c >= ' ' && c != '\x7f' })
+			switch {
+			case !ok:
+				return nil, st.trap("Unterminated quoted local part")
+			case c == '\\':
+				if _, ok := st.next(func(c byte) bool { return c >= ' ' && c != '\x7f' }); !ok {
+					return nil, st.trap("Invalid escape in quoted local part")
+				}
+				continue
+			case c != '"':
+				continue
+			}
+			break
+		}
+		addr.Local = st.Source[begin:st.Pos]
+	} else {
+		local, err := st.dotAtom("local part")
+		if err != nil {
+			return nil, err
+		}
+		addr.Local = local
+	}
+	if err := st.expectByte('@', "after local part"); err != nil {
+		return nil, err
+	}
+	begin = st.Pos
+	if _, ok := st.next(func(c byte) bool { return c == '[' }); ok {
+		st.skipWhile(func(c byte) bool { return c > ' ' && c < '\x7f' && c != '[' && c != ']' && c != '\\' })
+		if err := st.expectByte(']', "closing domain literal"); err != nil {
+			return nil, err
+		}
+		addr.Domain = st.Source[begin:st.Pos]
+	} else {
+		domain, err := st.dotAtom("domain")
+		if err != nil {
+			return nil, err
+		}
+		addr.Domain = domain
+	}
+	return addr, nil
+}
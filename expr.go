@@ -0,0 +1,129 @@
+package parsec
+
+type Fixity int
+
+const (
+	InfixLeft Fixity = iota
+	InfixRight
+	InfixNone
+	Prefix
+	Postfix
+)
+
+// Operator is an entry in an operator table. Op parses the operator and
+// returns the function combining its operands: a
+// func(x, y interface{}) interface{} for infix operators and a
+// func(x interface{}) interface{} for prefix and postfix operators.
+type Operator struct {
+	Fixity Fixity
+	Op     Parser
+}
+
+// BuildExpression returns a parser for expressions made of terms parsed by
+// term and the operators in table, which lists precedence levels from the
+// tightest binding to the loosest. Prefix and postfix operators may be
+// repeated; a non-associative operator may not follow an operand of the
+// same level. Operators should consume any whitespace following them, as
+// should term.
+func BuildExpression(table [][]Operator, term Parser) Parser {
+	for _, level := range table {
+		term = buildLevel(level, term)
+	}
+	return term
+}
+
+func buildLevel(ops []Operator, term Parser) Parser {
+	var choice [5]Parser
+	for _, op := range ops {
+		if choice[op.Fixity] == nil {
+			choice[op.Fixity] = op.Op
+		} else {
+			choice[op.Fixity] = choice[op.Fixity].Or(op.Op)
+		}
+	}
+	operand := term
+	if prefix := choice[Prefix]; prefix != nil {
+		operand = Many(prefix).Bind(func(fs interface{}) Parser {
+			return term.Bind(func(x interface{}) Parser {
+				fs := fs.([]interface{})
+				for i := len(fs) - 1; i >= 0; i-- {
+					x = fs[i].(func(interface{}) interface{})(x)
+				}
+				return Return(x)
+			})
+		})
+	}
+	if postfix := choice[Postfix]; postfix != nil {
+		operand = operand.Bind(func(x interface{}) Parser {
+			return Many(postfix).Bind(func(fs interface{}) Parser {
+				for _, f := range fs.([]interface{}) {
+					x = f.(func(interface{}) interface{})(x)
+				}
+				return Return(x)
+			})
+		})
+	}
+
+	var level Parser
+	level = func(st *ParseState) (interface{}, error) {
+		x, err := operand(st)
+		if err != nil {
+			return nil, err
+		}
+		for {
+			f, ok, err := optional(st, choice[InfixLeft])
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				y, err := operand(st)
+				if err != nil {
+					return nil, err
+				}
+				x = f.(func(x, y interface{}) interface{})(x, y)
+				continue
+			}
+			if f, ok, err = optional(st, choice[InfixRight]); err != nil {
+				return nil, err
+			} else if ok {
+				y, err := level(st)
+				if err != nil {
+					return nil, err
+				}
+				return f.(func(x, y interface{}) interface{})(x, y), nil
+			}
+			if f, ok, err = optional(st, choice[InfixNone]); err != nil {
+				return nil, err
+			} else if ok {
+				y, err := operand(st)
+				if err != nil {
+					return nil, err
+				}
+				x = f.(func(x, y interface{}) interface{})(x, y)
+				start := st.Position()
+				if _, ok, _ := optional(st, Try(choice[InfixNone])); ok {
+					return nil, st.trapAt(start, "Non-associative operator used in sequence")
+				}
+			}
+			return x, nil
+		}
+	}
+	return level
+}
+
+// optional runs p if it is not nil, reporting whether it succeeded. Failure
+// without consuming input is not an error.
+func optional(st *ParseState, p Parser) (interface{}, bool, error) {
+	if p == nil {
+		return nil, false, nil
+	}
+	pos := st.Pos
+	x, err := p(st)
+	if err != nil {
+		if st.Pos == pos && !st.cut {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return x, true, nil
+}
@@ -0,0 +1,108 @@
+package parsec
+
+import "unsafe"
+
+// Feeder runs a parser over input that arrives in pieces, such as data read
+// from a socket. Input is buffered until it holds a complete value; a parse
+// that fails or stops at the end of the buffered input is taken to need
+// more data rather than to be finished, so results are the same however
+// the input was split. Positions in results and errors are relative to the
+// whole stream.
+//
+// The parser is rerun from the start of the unconsumed input after every
+// Append, so values should be small relative to the stream, like the lines
+// or messages of a protocol. The buffered input is not copied for each
+// run: strings taken from it, such as Netstring payloads, share the
+// Feeder's memory, which is never overwritten.
+type Feeder struct {
+	// User is the user state each parse starts with, and is updated to the
+	// user state a parse ends with when it yields a value. As a parse may
+	// be rerun once more input arrives, parsers should replace User rather
+	// than modify it.
+	User interface{}
+
+	p      Parser
+	buf    []byte
+	offset int
+	line   int
+	closed bool
+	err    error
+}
+
+func NewFeeder(p Parser) *Feeder {
+	return &Feeder{p: p, line: 1}
+}
+
+// Append adds data to the buffered input.
+func (f *Feeder) Append(data []byte) {
+	f.buf = append(f.buf, data...)
+}
+
+// Next parses one value from the buffered input and consumes it. ok is
+// false with a nil error if the buffered input is empty or only the prefix
+// of a value, in which case more must be appended first. Once Next returns
+// an error, it keeps returning it.
+func (f *Feeder) Next() (x interface{}, ok bool, err error) {
+	if f.err != nil {
+		return nil, false, f.err
+	}
+	if len(f.buf) == 0 {
+		return nil, false, nil
+	}
+	st := ParseState{Source: unsafe.String(unsafe.SliceData(f.buf), len(f.buf)), Line: f.line, User: f.User, origin: []offsetMapping{{pos: 0, orig: f.offset}}}
+	x, err = f.p(&st)
+	if st.sawEnd && !f.closed {
+		return nil, false, nil
+	}
+	if err == nil && st.Pos == 0 {
+		err = st.trap("Parser succeeded without consuming input")
+	}
+	if err != nil {
+		f.err = err
+		return nil, false, err
+	}
+	f.buf = f.buf[st.Pos:]
+	f.offset += st.Pos
+	f.line = st.Line
+	f.User = st.User
+	return x, true, nil
+}
+
+// Feed appends data and returns every value completed by it.
+func (f *Feeder) Feed(data []byte) ([]interface{}, error) {
+	f.Append(data)
+	return f.drain()
+}
+
+// Close marks the end of the input and returns the values left in the
+// buffer. Input that does not form a complete value is an error.
+func (f *Feeder) Close() ([]interface{}, error) {
+	f.closed = true
+	return f.drain()
+}
+
+func (f *Feeder) drain() ([]interface{}, error) {
+	var xs []interface{}
+	for {
+		x, ok, err := f.Next()
+		if err != nil {
+			return xs, err
+		}
+		if !ok {
+			return xs, nil
+		}
+		xs = append(xs, x)
+	}
+}
+
+// Buffered returns the input appended but not yet consumed, such as the
+// body following a message header. It must not be modified, and is valid
+// until the next Append.
+func (f *Feeder) Buffered() []byte {
+	return f.buf
+}
+
+// Position returns the stream position of the first unconsumed byte.
+func (f *Feeder) Position() Position {
+	return Position{Offset: f.offset, Line: f.line}
+}
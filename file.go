@@ -0,0 +1,112 @@
+package parsec
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"unsafe"
+)
+
+// streamSize is the file size above which ParseFileFunc and ParseFSFunc
+// stream a file instead of reading it whole.
+const streamSize = 16 << 20
+
+// ParseFile parses the file at path with p. Errors from the parse carry
+// path in their File field. The whole file is read into memory, since a
+// single result may need any of its input; ParseFileFunc parses a file of
+// records, such as a log, without holding all of a large one.
+func ParseFile(p Parser, path string, opts ...ParseOption) (interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseData(p, path, data, opts)
+}
+
+// ParseFS parses the file name in fsys with p, reading all of it as
+// ParseFile does. Errors from the parse carry name in their File field.
+func ParseFS(p Parser, fsys fs.FS, name string, opts ...ParseOption) (interface{}, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return parseData(p, name, data, opts)
+}
+
+// parseData parses data in place rather than copying it to a string, as
+// nothing else holds it, so that a large file takes only its own size in
+// memory.
+func parseData(p Parser, name string, data []byte, opts []ParseOption) (interface{}, error) {
+	st := ParseState{Source: unsafe.String(unsafe.SliceData(data), len(data)), Line: 1, file: name}
+	for _, opt := range opts {
+		opt(&st)
+	}
+	if st.inputErr != nil {
+		return nil, st.inputErr
+	}
+	return st.finish(p(&st))
+}
+
+// ParseFileFunc parses the file at path as a series of p, such as the
+// records of a log, calling fn with each result and its span. Files of up
+// to 16 MiB are read whole and parsed in place; larger ones are streamed,
+// as by ParseStreamFunc, so that only the record being parsed is held in
+// memory. p must therefore follow the Feeder conventions, under which both
+// give the same results. Errors from the parse carry path in their File
+// field, and an error from fn stops the parse and is returned.
+func ParseFileFunc(p Parser, path string, fn func(Spanned) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return parseFileFunc(p, path, f, fn)
+}
+
+// ParseFSFunc is ParseFileFunc for the file name in fsys.
+func ParseFSFunc(p Parser, fsys fs.FS, name string, fn func(Spanned) error) error {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return parseFileFunc(p, name, f, fn)
+}
+
+func parseFileFunc(p Parser, name string, f fs.File, fn func(Spanned) error) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() > streamSize {
+		var fnErr error
+		err := p.ParseStreamFunc(f, func(x Spanned) error {
+			fnErr = fn(x)
+			return fnErr
+		})
+		if pe, ok := err.(ParseErr); ok && fnErr == nil {
+			pe.File = name
+			return pe
+		}
+		return err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	st := ParseState{Source: unsafe.String(unsafe.SliceData(data), len(data)), Line: 1, file: name}
+	for st.Pos < len(st.Source) {
+		start := st.Position()
+		x, err := p(&st)
+		if err == nil && st.Pos == start.Offset {
+			err = st.trap("Parser succeeded without consuming input")
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(Spanned{Value: x, Span: Span{Start: start, End: st.Position()}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+// Package fixedwidth reads records whose fields occupy fixed byte ranges
+// of a line, as in mainframe exports and bank statement files.
+package fixedwidth
+
+import (
+	"strings"
+
+	"parsec"
+)
+
+type Trim int
+
+const (
+	// TrimBoth removes padding on both sides of a field.
+	TrimBoth Trim = iota
+	// TrimRight removes padding after left-aligned text.
+	TrimRight
+	// TrimLeft removes padding before right-aligned text, such as
+	// zero-padded numbers. A field of nothing but padding keeps its last
+	// byte, so that a zero-padded 0 is read as "0" and not "".
+	TrimLeft
+	// TrimNone keeps fields as they are.
+	TrimNone
+)
+
+type Column struct {
+	Name  string
+	Width int
+	// Pad is the padding byte removed according to Trim. Defaults to ' '.
+	Pad  byte
+	Trim Trim
+	// Parser, if not nil, parses the trimmed field and must consume all
+	// of it. Otherwise the field's value is its trimmed text.
+	Parser parsec.Parser
+}
+
+type Field struct {
+	Name  string
+	Value interface{}
+	// Text is the trimmed text of the field.
+	Text string
+	Span parsec.Span
+}
+
+type Record []Field
+
+// Get returns the value of the field with the given name.
+func (r Record) Get(name string) (interface{}, bool) {
+	for _, f := range r {
+		if f.Name == name {
+			return f.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Layout describes the columns of a line, from left to right.
+type Layout struct {
+	Columns []Column
+	// Ragged accepts lines that end before the last column does; the
+	// missing part of the line is treated as padding.
+	Ragged bool
+}
+
+func (l Layout) width() int {
+	w := 0
+	for _, c := range l.Columns {
+		w += c.Width
+	}
+	return w
+}
+
+func (c Column) trim(begin, end int, line string) (int, int) {
+	pad := c.Pad
+	if pad == 0 {
+		pad = ' '
+	}
+	switch c.Trim {
+	case TrimBoth:
+		for begin < end && line[begin] == pad {
+			begin++
+		}
+	case TrimLeft:
+		for begin < end-1 && line[begin] == pad {
+			begin++
+		}
+	}
+	if c.Trim == TrimBoth || c.Trim == TrimRight {
+		for end > begin && line[end-1] == pad {
+			end--
+		}
+	}
+	return begin, end
+}
+
+// at returns the position of byte offset pos on the current line.
+func at(st *parsec.ParseState, pos int) parsec.Position {
+	saved := st.Pos
+	st.Pos = pos
+	p := st.Position()
+	st.Pos = saved
+	return p
+}
+
+// Line returns a parser for one line, including its line terminator,
+// returning a Record with a field per column. A line of the wrong length
+// is an error, as is a field its column's parser rejects.
+func (l Layout) Line() parsec.Parser {
+	width := l.width()
+	return func(st *parsec.ParseState) (interface{}, error) {
+		begin := st.Pos
+		end := strings.IndexAny(st.Source[begin:], "\r\n")
+		if end < 0 {
+			end = len(st.Source)
+		} else {
+			end += begin
+		}
+		if n := end - begin; n > width || n < width && !l.Ragged {
+			return nil, st.ErrorAt(st.Position(), "Line is %d bytes long but the layout is %d", n, width)
+		}
+		record := make(Record, len(l.Columns))
+		pos := begin
+		for i, c := range l.Columns {
+			from, to := min(pos, end), min(pos+c.Width, end)
+			from, to = c.trim(from, to, st.Source)
+			pos += c.Width
+			f := Field{Name: c.Name, Text: st.Source[from:to], Span: parsec.Span{Start: at(st, from), End: at(st, to)}}
+			f.Value = f.Text
+			if c.Parser != nil {
+				source := st.Source
+				st.Source, st.Pos = source[:to], from
+				x, err := c.Parser(st)
+				if err == nil && st.Pos < to {
+					err = st.ErrorAt(st.Position(), "Unexpected text in column %s", c.Name)
+				}
+				st.Source = source
+				if err != nil {
+					return nil, err
+				}
+				f.Value = x
+			}
+			record[i] = f
+		}
+		st.Pos = end
+		if _, err := parsec.Eol(st); err != nil {
+			return nil, err
+		}
+		return record, nil
+	}
+}
+
+// Parse parses every line of source and returns the records.
+func (l Layout) Parse(source string) ([]Record, error) {
+	x, err := parsec.ManyTill(l.Line(), parsec.Eof).Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]Record, len(x.([]interface{})))
+	for i, r := range x.([]interface{}) {
+		records[i] = r.(Record)
+	}
+	return records, nil
+}
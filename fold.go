@@ -0,0 +1,88 @@
+package parsec
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// WithCaseFold makes character and literal parsers match case-insensitively
+// for the whole parse, using Unicode simple case folding.
+func WithCaseFold() ParseOption {
+	return func(st *ParseState) {
+		st.fold = true
+	}
+}
+
+// Fold runs p with case-insensitive matching enabled.
+func Fold(p Parser) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		fold := st.fold
+		st.fold = true
+		defer func() { st.fold = fold }()
+		return p(st)
+	}
+}
+
+func OneOfFold(set string) Parser {
+	return Fold(RuneOneOf(set))
+}
+
+func StringFold(s string) Parser {
+	return Fold(String(s))
+}
+
+func equalFold(a, b rune) bool {
+	if a == b {
+		return true
+	}
+	for r := unicode.SimpleFold(a); r != a; r = unicode.SimpleFold(r) {
+		if r == b {
+			return true
+		}
+	}
+	return false
+}
+
+// sameByte only folds ASCII, since other bytes are parts of UTF-8 sequences.
+func (st *ParseState) sameByte(a, b byte) bool {
+	return a == b || st.fold && a < utf8.RuneSelf && b < utf8.RuneSelf && equalFold(rune(a), rune(b))
+}
+
+func (st *ParseState) byteIn(set []byte, c byte) bool {
+	for _, b := range set {
+		if st.sameByte(b, c) {
+			return true
+		}
+	}
+	return false
+}
+
+func (st *ParseState) sameRune(a, b rune) bool {
+	return a == b || st.fold && equalFold(a, b)
+}
+
+func (st *ParseState) runeIn(set string, r rune) bool {
+	if !st.fold {
+		return strings.ContainsRune(set, r)
+	}
+	for _, c := range set {
+		if equalFold(c, r) {
+			return true
+		}
+	}
+	return false
+}
+
+func (st *ParseState) stringFold(s string) (interface{}, error) {
+	oldPos, oldLine := st.Pos, st.Line
+	for _, c := range s {
+		r, size, err := st.peekRune()
+		if err != nil || !equalFold(r, c) {
+			st.Pos, st.Line = oldPos, oldLine
+			return nil, st.trap("Expected '%s'", s)
+		}
+		st.advance(size)
+	}
+	return s, nil
+}
@@ -0,0 +1,81 @@
+package parsec
+
+import "strings"
+
+// FrontMatter is a metadata block delimited by --- lines at the start of a
+// document, as used by static site generators, together with the body that
+// follows it.
+type FrontMatter struct {
+	Raw       string // the block's lines, without the delimiters
+	Span      Span   // the block, including the delimiters
+	Body      string
+	BodyStart Position
+}
+
+// FrontMatterBlock parses a front-matter block: a line holding ---, any
+// number of lines, and a line holding --- or .... It leaves the body
+// unconsumed, so the document's grammar can follow it directly, and fails
+// without consuming input if the document has no front matter.
+func FrontMatterBlock(st *ParseState) (interface{}, error) {
+	start, begin := st.Position(), st.Pos
+	open := delimiterLine(st.Source[st.Pos:], "---")
+	if open == 0 {
+		return nil, st.trap("Expected front matter")
+	}
+	for i := st.Pos + open; i < len(st.Source); {
+		n := delimiterLine(st.Source[i:], "---")
+		if n == 0 {
+			n = delimiterLine(st.Source[i:], "...")
+		}
+		if n > 0 {
+			st.advance(i + n - st.Pos)
+			return FrontMatter{
+				Raw:       st.Source[begin+open : i],
+				Span:      Span{Start: start, End: st.Position()},
+				Body:      st.Source[st.Pos:],
+				BodyStart: st.Position(),
+			}, nil
+		}
+		if eol := strings.IndexByte(st.Source[i:], '\n'); eol >= 0 {
+			i += eol + 1
+		} else {
+			break
+		}
+	}
+	st.sawEnd = true
+	return nil, st.trapAt(start, "Unterminated front matter")
+}
+
+// delimiterLine returns the length of the line at the start of s, including
+// its line break, if it holds only delim and trailing spaces, or 0.
+func delimiterLine(s, delim string) int {
+	if !strings.HasPrefix(s, delim) {
+		return 0
+	}
+	n := len(delim)
+	for n < len(s) && (s[n] == ' ' || s[n] == '\t') {
+		n++
+	}
+	switch {
+	case n == len(s):
+		return n
+	case s[n] == '\n':
+		return n + 1
+	case s[n] == '\r' && n+1 < len(s) && s[n+1] == '\n':
+		return n + 2
+	}
+	return 0
+}
+
+// ParseBody parses the body with p, reporting positions relative to the
+// whole document.
+func (fm FrontMatter) ParseBody(p Parser, opts ...ParseOption) (interface{}, error) {
+	st := ParseState{Source: fm.Body, Line: fm.BodyStart.Line, origin: []offsetMapping{{pos: 0, orig: fm.BodyStart.Offset}}}
+	for _, opt := range opts {
+		opt(&st)
+	}
+	if st.inputErr != nil {
+		return nil, st.inputErr
+	}
+	return p(&st)
+}
@@ -0,0 +1,260 @@
+// Package glob parses glob patterns into a syntax tree and matches paths
+// against them. A * matches any run of characters other than /, and a **
+// that makes up a whole path segment matches any run of characters, or
+// with the / after it any number of whole directories, none included, so
+// that a/**/b matches a/b and **/*.go matches x.go. A ?
+// matches any single character other than /. Brackets enclose a character
+// class such as [a-z], negated by a leading ! or ^. Braces enclose
+// comma-separated alternatives such as {a,b}, which may nest. A backslash
+// escapes the next character.
+package glob
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"parsec"
+)
+
+type Kind int
+
+const (
+	Literal Kind = iota
+	Star
+	DoubleStar
+	Any
+	Class
+	Alternation
+)
+
+type Range struct {
+	Lo, Hi rune
+}
+
+type Node struct {
+	Kind Kind
+	// Text is the literal text, or "/" for a DoubleStar followed by /.
+	Text string
+	// Ranges and Negated describe a character class.
+	Ranges  []Range
+	Negated bool
+	// Alternatives holds the sequences of an alternation.
+	Alternatives [][]*Node
+	Span         parsec.Span
+}
+
+// Pattern is a parsed glob.
+type Pattern struct {
+	Nodes  []*Node
+	Source string
+}
+
+func node(kind Kind) func(interface{}) parsec.Parser {
+	return func(interface{}) parsec.Parser {
+		return parsec.Return(&Node{Kind: kind})
+	}
+}
+
+// doubleStar parses ** when it makes up a whole path segment, along with
+// the / after it.
+func doubleStar(st *parsec.ParseState) (interface{}, error) {
+	s := st.Source[st.Pos:]
+	if !strings.HasPrefix(s, "**") || st.Pos > 0 && st.Source[st.Pos-1] != '/' || len(s) > 2 && s[2] != '/' && s[2] != '}' && s[2] != ',' {
+		return parsec.Fail("Expected **")(st)
+	}
+	st.Pos += 2
+	if len(s) > 2 && s[2] == '/' {
+		st.Pos++
+		return &Node{Kind: DoubleStar, Text: "/"}, nil
+	}
+	return &Node{Kind: DoubleStar}, nil
+}
+
+var escaped = parsec.Char('\\').Then(parsec.AnyRune)
+
+// classChar parses a character within a class, where ] must be escaped
+// unless it comes first.
+var classChar = escaped.Or(parsec.RuneNoneOf("]"))
+
+var classRange = classChar.Bind(func(lo interface{}) parsec.Parser {
+	hi := parsec.Try(parsec.Char('-').Then(classChar))
+	return hi.Or(parsec.Return(lo)).Bind(func(hi interface{}) parsec.Parser {
+		return parsec.Return(Range{lo.(rune), hi.(rune)})
+	})
+})
+
+// class parses a bracket expression. Ranges must not be reversed.
+func class(st *parsec.ParseState) (interface{}, error) {
+	start := st.Position()
+	if _, err := parsec.Char('[')(st); err != nil {
+		return nil, err
+	}
+	n := &Node{Kind: Class}
+	if _, ok, _ := optional(st, parsec.OneOf([]byte("!^"))); ok {
+		n.Negated = true
+	}
+	var first []interface{}
+	if _, ok, _ := optional(st, parsec.Char(']')); ok {
+		first = []interface{}{Range{']', ']'}}
+	}
+	x, err := parsec.Many(classRange)(st)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := parsec.Char(']')(st); err != nil {
+		return nil, st.ErrorAt(start, "Unterminated character class")
+	}
+	for _, r := range append(first, x.([]interface{})...) {
+		r := r.(Range)
+		if r.Lo > r.Hi {
+			return nil, st.ErrorAt(start, "Invalid range %c-%c in character class", r.Lo, r.Hi)
+		}
+		n.Ranges = append(n.Ranges, r)
+	}
+	if len(n.Ranges) == 0 {
+		return nil, st.ErrorAt(start, "Empty character class")
+	}
+	return n, nil
+}
+
+func optional(st *parsec.ParseState, p parsec.Parser) (interface{}, bool, error) {
+	pos := st.Pos
+	x, err := p(st)
+	if err != nil && st.Pos == pos {
+		return nil, false, nil
+	}
+	return x, err == nil, err
+}
+
+// sequence returns a parser for a run of glob elements. Inside an
+// alternation, commas and closing braces end the run.
+func sequence(inBraces bool) parsec.Parser {
+	stop := "*?[{\\"
+	if inBraces {
+		stop += ",}"
+	}
+	literal := parsec.Many1(escaped.Or(parsec.RuneNoneOf(stop))).ToString().Bind(func(x interface{}) parsec.Parser {
+		return parsec.Return(&Node{Kind: Literal, Text: x.(string)})
+	})
+	element := parsec.Parser(doubleStar).
+		Or(parsec.Char('*').Bind(node(Star))).
+		Or(parsec.Char('?').Bind(node(Any))).
+		Or(class).
+		Or(alternation).
+		Or(literal).
+		WithSpan().Bind(func(x interface{}) parsec.Parser {
+		s := x.(parsec.Spanned)
+		n := s.Value.(*Node)
+		n.Span = s.Span
+		return parsec.Return(n)
+	})
+	return parsec.Many(element).Bind(func(x interface{}) parsec.Parser {
+		nodes := []*Node{}
+		for _, n := range x.([]interface{}) {
+			nodes = append(nodes, n.(*Node))
+		}
+		return parsec.Return(nodes)
+	})
+}
+
+var alternative parsec.Parser
+
+func alternation(st *parsec.ParseState) (interface{}, error) {
+	start := st.Position()
+	if _, err := parsec.Char('{')(st); err != nil {
+		return nil, err
+	}
+	x, err := alternative.SepBy1(parsec.Char(','))(st)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := parsec.Char('}')(st); err != nil {
+		return nil, st.ErrorAt(start, "Unterminated alternation")
+	}
+	n := &Node{Kind: Alternation}
+	for _, alt := range x.([]interface{}) {
+		n.Alternatives = append(n.Alternatives, alt.([]*Node))
+	}
+	return n, nil
+}
+
+var pattern parsec.Parser
+
+func init() {
+	alternative = sequence(true)
+	pattern = sequence(false).Bind(func(x interface{}) parsec.Parser {
+		return parsec.Parser(parsec.Eof).Then(parsec.Return(x))
+	})
+}
+
+// Parse parses a glob pattern.
+func Parse(source string) (*Pattern, error) {
+	x, err := pattern.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	return &Pattern{Nodes: x.([]*Node), Source: source}, nil
+}
+
+// Match reports whether the whole of name matches the pattern.
+func (p *Pattern) Match(name string) bool {
+	return match(p.Nodes, name, func(rest string) bool { return rest == "" })
+}
+
+// match reports whether a prefix of name matches nodes and k accepts the
+// rest of name.
+func match(nodes []*Node, name string, k func(string) bool) bool {
+	if len(nodes) == 0 {
+		return k(name)
+	}
+	n, rest := nodes[0], nodes[1:]
+	switch n.Kind {
+	case Literal:
+		return strings.HasPrefix(name, n.Text) && match(rest, name[len(n.Text):], k)
+	case Star, DoubleStar:
+		if n.Text == "/" {
+			// No directories, or any run of them ending in /.
+			for i := 0; ; {
+				if match(rest, name[i:], k) {
+					return true
+				}
+				j := strings.IndexByte(name[i:], '/')
+				if j < 0 {
+					return false
+				}
+				i += j + 1
+			}
+		}
+		for i := 0; ; {
+			if match(rest, name[i:], k) {
+				return true
+			}
+			if i == len(name) || n.Kind == Star && name[i] == '/' {
+				return false
+			}
+			_, size := utf8.DecodeRuneInString(name[i:])
+			i += size
+		}
+	case Any, Class:
+		r, size := utf8.DecodeRuneInString(name)
+		if size == 0 || r == '/' || n.Kind == Class && !n.inClass(r) {
+			return false
+		}
+		return match(rest, name[size:], k)
+	}
+	for _, alt := range n.Alternatives {
+		if match(alt, name, func(s string) bool { return match(rest, s, k) }) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *Node) inClass(r rune) bool {
+	for _, rg := range n.Ranges {
+		if rg.Lo <= r && r <= rg.Hi {
+			return !n.Negated
+		}
+	}
+	return n.Negated
+}
@@ -0,0 +1,236 @@
+package parsec
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Grammar holds parsers registered as named rules, which refer to each
+// other by name, so that rules can be defined in any order and recursion
+// needs no package-level variables and init functions. Rules are labeled
+// with their names, so errors say "Expected expr" where a rule fails
+// without consuming input, and hooks see every rule. Left recursion, which
+// a combinator parser cannot handle, is reported when the parse reaches
+// it.
+//
+// Rules built with Lit, Choice and Ref inside the Define call registering
+// them are also described by a RuleInfo, which CompareRules uses to tell
+// how a grammar's language changed between versions.
+type Grammar struct {
+	rules      map[string]Parser
+	memoized   map[string]bool
+	referenced map[string]bool
+	info       map[string]RuleInfo
+	// pending collects what Lit, Choice and Ref record until Define
+	// claims it for the rule it registers.
+	pending RuleInfo
+}
+
+// RuleInfo describes the parts of a rule built with a Grammar's helpers.
+// Parts built with other combinators are opaque and not described.
+type RuleInfo struct {
+	Name string
+	// Literals are the strings matched with Lit, sorted and without
+	// duplicates.
+	Literals []string
+	// Refs are the rules referred to with Ref, sorted and without
+	// duplicates.
+	Refs []string
+	// Alternatives is the number of alternatives of the last Choice built
+	// for the rule, normally its top-level one, or 1 if it has none.
+	Alternatives int
+}
+
+func NewGrammar() *Grammar {
+	return &Grammar{rules: map[string]Parser{}, memoized: map[string]bool{}, referenced: map[string]bool{}, info: map[string]RuleInfo{}}
+}
+
+// Define registers p as the rule name. It panics if name is already
+// defined. What Lit, Choice and Ref recorded since the last Define, which
+// is normally what they recorded while building p, describes the rule, so
+// parsers built with them should not be shared between rules.
+func (g *Grammar) Define(name string, p Parser) {
+	if _, ok := g.rules[name]; ok {
+		panic("parsec: rule " + name + " defined twice")
+	}
+	g.rules[name] = g.rule(name, p.Label(name))
+	info := g.pending
+	info.Name = name
+	info.Literals = sortedSet(info.Literals)
+	info.Refs = sortedSet(info.Refs)
+	if info.Alternatives == 0 {
+		info.Alternatives = 1
+	}
+	g.info[name] = info
+	g.pending = RuleInfo{}
+}
+
+// Lit returns String(s), recording s as a literal of the rule being
+// defined.
+func (g *Grammar) Lit(s string) Parser {
+	g.pending.Literals = append(g.pending.Literals, s)
+	return String(s)
+}
+
+// Choice returns a parser trying each of ps in order, as p1.Or(p2)...
+// does, recording their number as the alternatives of the rule being
+// defined. It panics if ps is empty.
+func (g *Grammar) Choice(ps ...Parser) Parser {
+	if len(ps) == 0 {
+		panic("parsec: Choice of no alternatives")
+	}
+	g.pending.Alternatives = len(ps)
+	p := ps[0]
+	for _, q := range ps[1:] {
+		p = p.Or(q)
+	}
+	return p
+}
+
+// Rules describes the rules defined, sorted by name.
+func (g *Grammar) Rules() []RuleInfo {
+	rules := make([]RuleInfo, 0, len(g.info))
+	for _, info := range g.info {
+		rules = append(rules, info)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Name < rules[j].Name })
+	return rules
+}
+
+func sortedSet(ss []string) []string {
+	if len(ss) == 0 {
+		return nil
+	}
+	sort.Strings(ss)
+	out := ss[:1]
+	for _, s := range ss[1:] {
+		if s != out[len(out)-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// Memoize makes the rules named remember their result at each position of
+// a parse, so that backtracking over them does not parse the same input
+// again, at the cost of memory for every position they run at. A result
+// replayed from memory does not repeat the rule's hooks or changes to User.
+func (g *Grammar) Memoize(names ...string) {
+	for _, name := range names {
+		g.memoized[name] = true
+	}
+}
+
+// Ref returns a parser running the rule name, which may be defined after
+// the call, recording name as referred to by the rule being defined.
+func (g *Grammar) Ref(name string) Parser {
+	g.pending.Refs = append(g.pending.Refs, name)
+	return g.ref(name)
+}
+
+func (g *Grammar) ref(name string) Parser {
+	g.referenced[name] = true
+	return func(st *ParseState) (interface{}, error) {
+		p, ok := g.rules[name]
+		if !ok {
+			return nil, fmt.Errorf("parsec: rule %s is not defined", name)
+		}
+		return p(st)
+	}
+}
+
+// Check reports rules referenced with Ref but never defined.
+func (g *Grammar) Check() error {
+	var missing []string
+	for name := range g.referenced {
+		if _, ok := g.rules[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("parsec: undefined rules: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Parse checks the grammar and parses source with the rule start.
+func (g *Grammar) Parse(start, source string, opts ...ParseOption) (interface{}, error) {
+	if err := g.Check(); err != nil {
+		return nil, err
+	}
+	return g.ref(start).Parse(source, opts...)
+}
+
+type ruleKey struct {
+	g    *Grammar
+	name string
+	pos  int
+}
+
+type ruleEntry struct {
+	active bool
+	done   bool
+	x      interface{}
+	err    error
+	pos    int
+	line   int
+	cut    bool
+	sawEnd bool
+}
+
+// rule runs p as the rule name, failing as after Cut if the rule is
+// entered again at the same position before it returns, which would
+// recurse forever, and remembering its result if the rule is memoized.
+func (g *Grammar) rule(name string, p Parser) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		if st.rules == nil {
+			st.rules = map[ruleKey]*ruleEntry{}
+		}
+		key := ruleKey{g, name, st.Pos}
+		e := st.rules[key]
+		switch {
+		case e == nil:
+			e = &ruleEntry{}
+			st.rules[key] = e
+		case e.active:
+			// A grammar error, which no alternative should hide.
+			st.cut = true
+			return nil, st.trap("Left recursion in rule %s", name)
+		case e.done:
+			st.Pos, st.Line = e.pos, e.line
+			st.cut = st.cut || e.cut
+			st.sawEnd = st.sawEnd || e.sawEnd
+			return e.x, e.err
+		}
+		e.active = true
+		sawEnd := st.sawEnd
+		st.sawEnd = false
+		x, err := p(st)
+		e.active = false
+		if g.memoized[name] {
+			*e = ruleEntry{done: true, x: x, err: err, pos: st.Pos, line: st.Line, cut: st.cut, sawEnd: st.sawEnd}
+		} else {
+			delete(st.rules, key)
+		}
+		st.sawEnd = st.sawEnd || sawEnd
+		return x, err
+	}
+}
+
+// activeRules returns a table of the rules st is running, for a branch of
+// ParallelEither, which needs them to detect left recursion but cannot
+// share st's table.
+func (st *ParseState) activeRules() map[ruleKey]*ruleEntry {
+	var rules map[ruleKey]*ruleEntry
+	for key, e := range st.rules {
+		if e.active {
+			if rules == nil {
+				rules = map[ruleKey]*ruleEntry{}
+			}
+			rules[key] = &ruleEntry{active: true}
+		}
+	}
+	return rules
+}
@@ -0,0 +1,125 @@
+package parsec
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+var Graphemes = Many1(AnyGrapheme)
+
+type graphemeClass int
+
+const (
+	gcOther graphemeClass = iota
+	gcCR
+	gcLF
+	gcControl
+	gcExtend
+	gcZWJ
+	gcRegional
+	gcPictographic
+	gcL
+	gcV
+	gcT
+	gcLV
+	gcLVT
+)
+
+func classifyGrapheme(r rune) graphemeClass {
+	switch {
+	case r == '\r':
+		return gcCR
+	case r == '\n':
+		return gcLF
+	case r == 0x200D:
+		return gcZWJ
+	case unicode.Is(unicode.Cc, r), r == 0x2028, r == 0x2029:
+		return gcControl
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc),
+		r >= 0x1F3FB && r <= 0x1F3FF, // emoji skin tone modifiers
+		r >= 0xE0020 && r <= 0xE007F: // emoji tag sequences
+		return gcExtend
+	case r >= 0x1F1E6 && r <= 0x1F1FF:
+		return gcRegional
+	case r >= 0x1100 && r <= 0x115F, r >= 0xA960 && r <= 0xA97C:
+		return gcL
+	case r >= 0x1160 && r <= 0x11A7, r >= 0xD7B0 && r <= 0xD7C6:
+		return gcV
+	case r >= 0x11A8 && r <= 0x11FF, r >= 0xD7CB && r <= 0xD7FB:
+		return gcT
+	case r >= 0xAC00 && r <= 0xD7A3:
+		if (r-0xAC00)%28 == 0 {
+			return gcLV
+		}
+		return gcLVT
+	case r >= 0x1F000 && r <= 0x1FAFF, r >= 0x2600 && r <= 0x27BF, unicode.Is(unicode.So, r):
+		return gcPictographic
+	}
+	return gcOther
+}
+
+// graphemeBreak reports whether there is a cluster boundary between two
+// runes of class prev and next, following the rules of UAX #29 closely
+// enough for text in common use. riCount is the number of consecutive
+// regional indicators ending at prev, and afterPict whether prev is a ZWJ
+// (possibly after extenders) following a pictographic rune.
+func graphemeBreak(prev, next graphemeClass, riCount int, afterPict bool) bool {
+	switch {
+	case prev == gcCR && next == gcLF:
+		return false
+	case prev == gcCR, prev == gcLF, prev == gcControl:
+		return true
+	case next == gcCR, next == gcLF, next == gcControl:
+		return true
+	case prev == gcL && (next == gcL || next == gcV || next == gcLV || next == gcLVT):
+		return false
+	case (prev == gcLV || prev == gcV) && (next == gcV || next == gcT):
+		return false
+	case (prev == gcLVT || prev == gcT) && next == gcT:
+		return false
+	case next == gcExtend, next == gcZWJ:
+		return false
+	case prev == gcZWJ && next == gcPictographic && afterPict:
+		return false
+	case prev == gcRegional && next == gcRegional:
+		return riCount%2 == 0
+	}
+	return true
+}
+
+// AnyGrapheme consumes one extended grapheme cluster, such as a letter with
+// its combining marks or an emoji ZWJ sequence, and returns it as a string.
+func AnyGrapheme(st *ParseState) (interface{}, error) {
+	r, size, err := st.peekRune()
+	if err != nil {
+		return nil, err
+	}
+	start, end := st.Pos, st.Pos+size
+	prev := classifyGrapheme(r)
+	riCount, pict, afterPict := 0, prev == gcPictographic, false
+	if prev == gcRegional {
+		riCount = 1
+	}
+	for !st.atEnd(end) {
+		r, size := utf8.DecodeRuneInString(st.Source[end:])
+		if r == utf8.RuneError && size == 1 {
+			break
+		}
+		next := classifyGrapheme(r)
+		if graphemeBreak(prev, next, riCount, afterPict) {
+			break
+		}
+		if next == gcRegional {
+			riCount++
+		}
+		afterPict = next == gcZWJ && pict
+		if next == gcPictographic {
+			pict = true
+		} else if next != gcExtend && next != gcZWJ {
+			pict = false
+		}
+		prev, end = next, end+size
+	}
+	st.advance(end - start)
+	return st.Source[start:end], nil
+}
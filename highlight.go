@@ -0,0 +1,64 @@
+package parsec
+
+// Token is a span of input and the class a Highlighter gave it, such as
+// "keyword" or "string".
+type Token struct {
+	Span  Span
+	Class string
+}
+
+// Highlighter is Hooks turning labeled parsers into a stream of tokens for
+// syntax highlighting, so that the grammar used for parsing also drives an
+// editor. Each labeled parser with a class in Classes yields a token for
+// the input it consumed when it succeeds. Tokens do not overlap: a token
+// inside another splits it, leaving the outer class on the input around
+// the inner token, as for an escape sequence inside a string. Tokens from
+// an alternative that is abandoned are dropped when a labeled parser runs
+// again from before their end.
+type Highlighter struct {
+	Classes map[string]string
+	tokens  []Token
+	starts  []Position
+}
+
+func NewHighlighter(classes map[string]string) *Highlighter {
+	return &Highlighter{Classes: classes}
+}
+
+func (h *Highlighter) OnEnter(name string, pos Position) {
+	for len(h.tokens) > 0 && h.tokens[len(h.tokens)-1].Span.End.Offset > pos.Offset {
+		h.tokens = h.tokens[:len(h.tokens)-1]
+	}
+	h.starts = append(h.starts, pos)
+}
+
+func (h *Highlighter) OnExit(name string, pos Position, result interface{}, err error) {
+	start := h.starts[len(h.starts)-1]
+	h.starts = h.starts[:len(h.starts)-1]
+	class, ok := h.Classes[name]
+	if !ok || err != nil || pos.Offset <= start.Offset {
+		return
+	}
+	k := len(h.tokens)
+	for k > 0 && h.tokens[k-1].Span.Start.Offset >= start.Offset {
+		k--
+	}
+	inner := append([]Token(nil), h.tokens[k:]...)
+	h.tokens = h.tokens[:k]
+	at := start
+	for _, t := range inner {
+		if t.Span.Start.Offset > at.Offset {
+			h.tokens = append(h.tokens, Token{Span: Span{Start: at, End: t.Span.Start}, Class: class})
+		}
+		h.tokens = append(h.tokens, t)
+		at = t.Span.End
+	}
+	if pos.Offset > at.Offset {
+		h.tokens = append(h.tokens, Token{Span: Span{Start: at, End: pos}, Class: class})
+	}
+}
+
+// Tokens returns the tokens recorded, in input order.
+func (h *Highlighter) Tokens() []Token {
+	return h.tokens
+}
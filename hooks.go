@@ -0,0 +1,34 @@
+package parsec
+
+// Hooks observe labeled parsers as they run. OnEnter is called before the
+// parser is applied and OnExit after it returns, with the position reached
+// and the parser's result or error.
+type Hooks interface {
+	OnEnter(name string, pos Position)
+	OnExit(name string, pos Position, result interface{}, err error)
+}
+
+func WithHooks(h Hooks) ParseOption {
+	return func(st *ParseState) {
+		st.Hooks = h
+	}
+}
+
+// Label names p for hooks and error reporting. If p fails without consuming
+// input, the error is replaced by "Expected <name>".
+func (p Parser) Label(name string) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		start := st.Pos
+		if st.Hooks != nil {
+			st.Hooks.OnEnter(name, st.Position())
+		}
+		x, err := p(st)
+		if err != nil && st.Pos == start && !st.cut {
+			err = st.trap("Expected %s", name)
+		}
+		if st.Hooks != nil {
+			st.Hooks.OnExit(name, st.Position(), x, err)
+		}
+		return x, err
+	}
+}
@@ -0,0 +1,143 @@
+package parsec
+
+import (
+	"strings"
+)
+
+// HostnameOptions configures HostnameWith.
+type HostnameOptions struct {
+	// IDN accepts internationalized labels in their ASCII "xn--" form,
+	// checking that they are valid Punycode. Without it, labels with
+	// hyphens in the third and fourth positions are rejected as reserved.
+	IDN bool
+}
+
+func isLabelChar(c byte) bool {
+	return isDigit(c) || isLetter(c) || c == '-'
+}
+
+// Hostname parses a DNS hostname made of letter, digit and hyphen labels
+// separated by dots, with an optional trailing dot, and returns the labels
+// as a []string.
+func Hostname(st *ParseState) (interface{}, error) {
+	return hostname(st, HostnameOptions{})
+}
+
+func HostnameWith(opts HostnameOptions) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		return hostname(st, opts)
+	}
+}
+
+func hostname(st *ParseState, opts HostnameOptions) (interface{}, error) {
+	start, begin := st.Position(), st.Pos
+	var labels []string
+	for {
+		pos, mark := st.Position(), st.Pos
+		if st.skipWhile(isLabelChar) == 0 {
+			return nil, st.trap("Expected hostname label")
+		}
+		label := st.Source[mark:st.Pos]
+		switch {
+		case len(label) > 63:
+			return nil, st.trapAt(pos, "Hostname label longer than 63 characters")
+		case label[0] == '-' || label[len(label)-1] == '-':
+			return nil, st.trapAt(pos, "Hostname label must not start or end with '-'")
+		case len(label) >= 4 && label[2:4] == "--":
+			if !opts.IDN || !strings.EqualFold(label[:2], "xn") {
+				return nil, st.trapAt(pos, "Reserved hostname label %s", label)
+			}
+			if _, ok := decodePunycode(strings.ToLower(label[4:])); !ok {
+				return nil, st.trapAt(pos, "Invalid Punycode in hostname label %s", label)
+			}
+		}
+		labels = append(labels, label)
+		if _, ok := st.next(func(c byte) bool { return c == '.' }); !ok {
+			break
+		}
+		if st.atEnd(st.Pos) || !isLabelChar(st.Source[st.Pos]) {
+			break
+		}
+	}
+	if st.Pos-begin > 254 || st.Pos-begin == 254 && st.Source[st.Pos-1] != '.' {
+		return nil, st.trapAt(start, "Hostname longer than 253 characters")
+	}
+	return labels, nil
+}
+
+// decodePunycode decodes the part of an IDN label following "xn--", as
+// specified by RFC 3492.
+func decodePunycode(s string) (string, bool) {
+	const (
+		base, tmin, tmax, skew, damp = 36, 1, 26, 38, 700
+	)
+	var output []rune
+	if i := strings.LastIndexByte(s, '-'); i >= 0 {
+		for _, c := range []byte(s[:i]) {
+			if c >= 0x80 {
+				return "", false
+			}
+			output = append(output, rune(c))
+		}
+		s = s[i+1:]
+	}
+	adapt := func(delta, numPoints int, first bool) int {
+		if first {
+			delta /= damp
+		} else {
+			delta /= 2
+		}
+		delta += delta / numPoints
+		k := 0
+		for delta > ((base-tmin)*tmax)/2 {
+			delta /= base - tmin
+			k += base
+		}
+		return k + (base-tmin+1)*delta/(delta+skew)
+	}
+	n, bias, i := 128, 72, 0
+	for pos := 0; pos < len(s); {
+		oldi, w := i, 1
+		for k := base; ; k += base {
+			if pos >= len(s) {
+				return "", false
+			}
+			c := s[pos]
+			pos++
+			var digit int
+			switch {
+			case 'a' <= c && c <= 'z':
+				digit = int(c - 'a')
+			case '0' <= c && c <= '9':
+				digit = int(c-'0') + 26
+			default:
+				return "", false
+			}
+			if digit > (1<<31-1-i)/w {
+				return "", false
+			}
+			i += digit * w
+			t := k - bias
+			if t < tmin {
+				t = tmin
+			} else if t > tmax {
+				t = tmax
+			}
+			if digit < t {
+				break
+			}
+			w *= base - t
+		}
+		bias = adapt(i-oldi, len(output)+1, oldi == 0)
+		n += i / (len(output) + 1)
+		i %= len(output) + 1
+		if n > 0x10FFFF {
+			return "", false
+		}
+		output = append(output, 0)
+		copy(output[i+1:], output[i:])
+		output[i] = rune(n)
+		i++
+	}
+	return string(output), true
+}
@@ -0,0 +1,232 @@
+package httpmsg
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"parsec"
+)
+
+type SameSite int
+
+const (
+	// SameSiteDefault means the attribute was absent or not recognized.
+	SameSiteDefault SameSite = iota
+	SameSiteLax
+	SameSiteStrict
+	SameSiteNone
+)
+
+// Cookie is a cookie from a Cookie header, which sets only Name, Value,
+// Quoted and Span, or from a Set-Cookie header.
+type Cookie struct {
+	Name  string
+	Value string
+	// Quoted is set if the value was enclosed in double quotes, which are
+	// not part of Value.
+	Quoted bool
+
+	Path       string
+	Domain     string
+	Expires    time.Time
+	RawExpires string
+	// MaxAge is 0 if no Max-Age attribute was given, negative if it asks
+	// for the cookie to be deleted now, and the lifetime in seconds
+	// otherwise.
+	MaxAge      int
+	Secure      bool
+	HttpOnly    bool
+	Partitioned bool
+	SameSite    SameSite
+	// Unparsed holds unknown attributes verbatim.
+	Unparsed []string
+
+	// Span covers the name and value.
+	Span parsec.Span
+}
+
+// until returns the input up to the first byte in stop or control
+// character other than HTAB, without surrounding whitespace.
+func until(stop string) parsec.Parser {
+	stop += string(controls("", "\t"))
+	return func(st *parsec.ParseState) (interface{}, error) {
+		n := strings.IndexAny(st.Source[st.Pos:], stop)
+		if n < 0 {
+			n = len(st.Source) - st.Pos
+		}
+		s := st.Source[st.Pos : st.Pos+n]
+		st.Pos += n
+		return strings.Trim(s, " \t"), nil
+	}
+}
+
+var attrName = until("=;")
+var attrValue = until(";")
+var semicolon = parsec.Char(';')
+
+// cookiePair parses name=value. Without an '=' the text is taken as the
+// value of a cookie with an empty name, as browsers do, unless strict.
+func cookiePair(st *parsec.ParseState, strict bool) (Cookie, error) {
+	start := st.Position()
+	x, _ := attrName(st)
+	c := Cookie{Name: x.(string)}
+	if _, err := parsec.Char('=')(st); err != nil {
+		if strict {
+			return c, st.ErrorAt(start, "Expected '=' in cookie")
+		}
+		c.Name, c.Value = "", c.Name
+	} else {
+		x, _ = attrValue(st)
+		c.Value = x.(string)
+	}
+	if n := len(c.Value); n >= 2 && c.Value[0] == '"' && c.Value[n-1] == '"' {
+		c.Value, c.Quoted = c.Value[1:n-1], true
+	}
+	if c.Name == "" && c.Value == "" && !c.Quoted {
+		return c, st.ErrorAt(start, "Empty cookie")
+	}
+	c.Span = parsec.Span{Start: start, End: st.Position()}
+	return c, nil
+}
+
+// end fails unless the whole header value has been read, so that control
+// characters are reported rather than silently dropping what follows.
+func end(st *parsec.ParseState) error {
+	_, err := parsec.Eof(st)
+	return err
+}
+
+// CookieParser parses the value of a Cookie header and returns its cookies
+// as a []Cookie. Empty entries, as left by a stray or trailing ';', are
+// skipped.
+func CookieParser(st *parsec.ParseState) (interface{}, error) {
+	cookies := []Cookie{}
+	for {
+		parsec.Spaces(st)
+		if st.Pos < len(st.Source) && st.Source[st.Pos] != ';' {
+			c, err := cookiePair(st, false)
+			if err != nil {
+				return nil, err
+			}
+			cookies = append(cookies, c)
+		}
+		if _, err := semicolon(st); err != nil {
+			return cookies, end(st)
+		}
+	}
+}
+
+var expiresLayouts = []string{
+	"Mon, 02-Jan-2006 15:04:05 MST",
+	"Mon, 02 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Monday, 02-Jan-2006 15:04:05 MST",
+	"Mon Jan 02 2006 15:04:05 MST",
+}
+
+var httpDate = parsec.Parser(parsec.HTTPDate).Bind(func(t interface{}) parsec.Parser {
+	return parsec.Parser(parsec.Eof).Then(parsec.Return(t))
+})
+
+// parseExpires accepts the HTTP date formats and a few variants servers
+// are known to send.
+func parseExpires(s string) (time.Time, bool) {
+	if t, err := httpDate.Parse(s); err == nil {
+		return t.(time.Time), true
+	}
+	for _, layout := range expiresLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// SetCookieParser parses the value of a Set-Cookie header and returns a
+// Cookie. Following RFC 6265, attribute names are case-insensitive and an
+// attribute with an invalid value is ignored rather than failing the
+// whole cookie.
+func SetCookieParser(st *parsec.ParseState) (interface{}, error) {
+	c, err := cookiePair(st, true)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, err := semicolon(st); err != nil {
+			break
+		}
+		x, _ := attrName(st)
+		name, value := x.(string), ""
+		hasValue := false
+		if _, err := parsec.Char('=')(st); err == nil {
+			x, _ = attrValue(st)
+			value, hasValue = x.(string), true
+		}
+		switch strings.ToLower(name) {
+		case "":
+		case "expires":
+			c.RawExpires = value
+			if t, ok := parseExpires(value); ok {
+				c.Expires = t
+			}
+		case "max-age":
+			if n, err := strconv.Atoi(value); err == nil && (value[0] == '-' || value[0] >= '0' && value[0] <= '9') {
+				if n <= 0 {
+					n = -1
+				}
+				c.MaxAge = n
+			}
+		case "domain":
+			if d := strings.ToLower(strings.TrimPrefix(value, ".")); d != "" {
+				c.Domain = d
+			}
+		case "path":
+			if strings.HasPrefix(value, "/") {
+				c.Path = value
+			}
+		case "secure":
+			c.Secure = true
+		case "httponly":
+			c.HttpOnly = true
+		case "partitioned":
+			c.Partitioned = true
+		case "samesite":
+			switch strings.ToLower(value) {
+			case "lax":
+				c.SameSite = SameSiteLax
+			case "strict":
+				c.SameSite = SameSiteStrict
+			case "none":
+				c.SameSite = SameSiteNone
+			}
+		default:
+			if hasValue {
+				name += "=" + value
+			}
+			c.Unparsed = append(c.Unparsed, name)
+		}
+	}
+	if err := end(st); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ParseCookie parses a Cookie header value.
+func ParseCookie(s string) ([]Cookie, error) {
+	x, err := parsec.Parser(CookieParser).Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	return x.([]Cookie), nil
+}
+
+// ParseSetCookie parses a Set-Cookie header value.
+func ParseSetCookie(s string) (Cookie, error) {
+	x, err := parsec.Parser(SetCookieParser).Parse(s)
+	if err != nil {
+		return Cookie{}, err
+	}
+	return x.(Cookie), nil
+}
@@ -0,0 +1,196 @@
+// Package httpmsg parses the start line and header block of HTTP/1.x
+// messages as specified in RFC 9112. The body is left alone: used with a
+// parsec.Feeder, RequestParser returns as soon as the empty line ending the
+// headers arrives, and the bytes that follow are available from the
+// Feeder's Buffered method.
+//
+// Parsing is strict where leniency is known to enable request smuggling:
+// whitespace between a field name and its colon, obsolete line folding
+// and bare CR line endings are all rejected. A bare LF is accepted as a
+// line ending.
+package httpmsg
+
+import (
+	"strings"
+
+	"parsec"
+)
+
+type RequestLine struct {
+	Method  string
+	Target  string
+	Version string
+}
+
+type StatusLine struct {
+	Version string
+	Code    int
+	Reason  string
+}
+
+type Header struct {
+	Name  string
+	Value string
+	Span  parsec.Span
+}
+
+type Headers []Header
+
+// Get returns the value of the first field with the given name, compared
+// case-insensitively.
+func (h Headers) Get(name string) (string, bool) {
+	for _, f := range h {
+		if strings.EqualFold(f.Name, name) {
+			return f.Value, true
+		}
+	}
+	return "", false
+}
+
+// Values returns the values of all fields with the given name, in order.
+func (h Headers) Values(name string) []string {
+	var vs []string
+	for _, f := range h {
+		if strings.EqualFold(f.Name, name) {
+			vs = append(vs, f.Value)
+		}
+	}
+	return vs
+}
+
+type Request struct {
+	RequestLine
+	Headers Headers
+}
+
+type Response struct {
+	StatusLine
+	Headers Headers
+}
+
+const tchars = "!#$%&'*+-.^_`|~0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// controls returns the ASCII control characters, DEL and the given extra
+// bytes, less those in allow.
+func controls(extra, allow string) []byte {
+	var set []byte
+	for c := 0; c < 0x20; c++ {
+		if strings.IndexByte(allow, byte(c)) < 0 {
+			set = append(set, byte(c))
+		}
+	}
+	return append(append(set, 0x7f), extra...)
+}
+
+// Token parses an RFC 9110 token, as used for methods and field names.
+var Token = parsec.Many1(parsec.OneOf([]byte(tchars))).ToString().Label("token")
+
+var sp = parsec.Char(' ')
+var ows = parsec.Spaces
+
+var crlf = parsec.Char('\r').Then(parsec.Char('\n').Or(parsec.Fail("Expected LF after CR"))).
+	Or(parsec.Char('\n')).
+	Or(parsec.Fail("Expected CRLF"))
+
+var version = parsec.String("HTTP/").Then(parsec.Digit).Bind(func(major interface{}) parsec.Parser {
+	return parsec.Char('.').Then(parsec.Digit).Bind(func(minor interface{}) parsec.Parser {
+		return parsec.Return("HTTP/" + string([]byte{major.(byte), '.', minor.(byte)}))
+	})
+}).Label("HTTP version")
+
+var target = parsec.Many1(parsec.NoneOf(controls(" ", ""))).ToString().Label("request target")
+
+var code = parsec.Digit.Bind(func(a interface{}) parsec.Parser {
+	return parsec.Digit.Bind(func(b interface{}) parsec.Parser {
+		return parsec.Digit.Bind(func(c interface{}) parsec.Parser {
+			return parsec.Return(int(a.(byte)-'0')*100 + int(b.(byte)-'0')*10 + int(c.(byte)-'0'))
+		})
+	})
+}).Label("status code")
+
+// text parses field content or a reason phrase: anything but controls
+// other than HTAB.
+var text = parsec.Many(parsec.NoneOf(controls("", "\t"))).ToString()
+
+// RequestLineParser parses a request line, including its line ending, and
+// returns a RequestLine.
+var RequestLineParser = Token.Label("method").Bind(func(m interface{}) parsec.Parser {
+	return sp.Then(target).Bind(func(t interface{}) parsec.Parser {
+		return sp.Then(version).Bind(func(v interface{}) parsec.Parser {
+			return crlf.Then(parsec.Return(RequestLine{Method: m.(string), Target: t.(string), Version: v.(string)}))
+		})
+	})
+})
+
+// StatusLineParser parses a status line, including its line ending, and
+// returns a StatusLine. The reason phrase may be empty.
+var StatusLineParser = version.Bind(func(v interface{}) parsec.Parser {
+	return sp.Then(code).Bind(func(c interface{}) parsec.Parser {
+		return sp.Then(text).Or(parsec.Return("")).Bind(func(r interface{}) parsec.Parser {
+			return crlf.Then(parsec.Return(StatusLine{Version: v.(string), Code: c.(int), Reason: r.(string)}))
+		})
+	})
+})
+
+// HeaderField parses one header field line and returns a Header with the
+// optional whitespace around its value removed. A field continued on the
+// next line by obsolete line folding is an error, so the parser reads the
+// first byte of the following line before it succeeds.
+func HeaderField(st *parsec.ParseState) (interface{}, error) {
+	start := st.Position()
+	name, err := Token.Label("header field name")(st)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := parsec.Space(st); err == nil {
+		return nil, st.ErrorAt(start, "Whitespace between header field name and colon")
+	}
+	if _, err := parsec.Char(':')(st); err != nil {
+		return nil, err
+	}
+	ows(st)
+	value, err := text(st)
+	if err != nil {
+		return nil, err
+	}
+	end := st.Position()
+	if _, err := crlf(st); err != nil {
+		return nil, err
+	}
+	fold := st.Position()
+	if _, err := parsec.Space(st); err == nil {
+		return nil, st.ErrorAt(fold, "Obsolete line folding in header field")
+	}
+	return Header{
+		Name:  name.(string),
+		Value: strings.TrimRight(value.(string), " \t"),
+		Span:  parsec.Span{Start: start, End: end},
+	}, nil
+}
+
+// HeaderBlock parses header fields up to and including the empty line that
+// ends them, and returns them as Headers.
+var HeaderBlock = parsec.Many(HeaderField).Bind(func(x interface{}) parsec.Parser {
+	hs := make(Headers, len(x.([]interface{})))
+	for i, h := range x.([]interface{}) {
+		hs[i] = h.(Header)
+	}
+	return crlf.Label("header field").Then(parsec.Return(hs))
+})
+
+// RequestParser parses a request line and header block and returns a
+// Request. Empty lines before the request line are skipped, as RFC 9112
+// recommends for servers.
+var RequestParser = parsec.SkipMany(crlf).Then(RequestLineParser).Bind(func(rl interface{}) parsec.Parser {
+	return HeaderBlock.Bind(func(hs interface{}) parsec.Parser {
+		return parsec.Return(Request{RequestLine: rl.(RequestLine), Headers: hs.(Headers)})
+	})
+})
+
+// ResponseParser parses a status line and header block and returns a
+// Response.
+var ResponseParser = StatusLineParser.Bind(func(sl interface{}) parsec.Parser {
+	return HeaderBlock.Bind(func(hs interface{}) parsec.Parser {
+		return parsec.Return(Response{StatusLine: sl.(StatusLine), Headers: hs.(Headers)})
+	})
+})
@@ -0,0 +1,219 @@
+package httpmsg
+
+import (
+	"sort"
+	"strings"
+
+	"parsec"
+)
+
+// MediaType is a media type such as text/html; charset=utf-8. Type,
+// Subtype and parameter names are lowercased, as they are
+// case-insensitive; parameter values are kept as given.
+type MediaType struct {
+	Type    string
+	Subtype string
+	Params  map[string]string
+	Span    parsec.Span
+}
+
+// AcceptRange is a media range from an Accept header, which may use "*"
+// as Subtype, or as both Type and Subtype.
+type AcceptRange struct {
+	MediaType
+	// Q is the weight given by the q parameter, 1 if absent. A range with
+	// weight 0 is not acceptable.
+	Q float64
+}
+
+var quotedPair = parsec.Char('\\').Then(parsec.NoneOf(controls("", "\t")))
+
+// QuotedString parses an RFC 9110 quoted-string and returns its value with
+// quoted pairs resolved.
+var QuotedString = parsec.Many(parsec.NoneOf(controls(`"\`, "\t")).Or(quotedPair)).ToString().
+	Between(parsec.Char('"'), parsec.Char('"').Label("closing quote"))
+
+var paramValue = Token.Or(QuotedString).Label("parameter value")
+
+// parameters parses the ";name=value" list following a media type into
+// m.Params. If weight is set it stops before a q parameter, which
+// separates the media type's parameters from the Accept extensions.
+func parameters(st *parsec.ParseState, m *MediaType, weight bool) error {
+	for {
+		pos := st.Pos
+		if _, err := parsec.Try(ows.Then(semicolon))(st); err != nil {
+			return nil
+		}
+		ows(st)
+		start := st.Position()
+		x, err := Token(st)
+		if err != nil {
+			continue
+		}
+		name := strings.ToLower(x.(string))
+		if weight && name == "q" {
+			st.Pos = pos
+			return nil
+		}
+		if _, err := parsec.Char('=')(st); err != nil {
+			return err
+		}
+		v, err := paramValue(st)
+		if err != nil {
+			return err
+		}
+		if _, ok := m.Params[name]; ok {
+			return st.ErrorAt(start, "Duplicate parameter %q", name)
+		}
+		m.Params[name] = v.(string)
+	}
+}
+
+func mediaType(st *parsec.ParseState, weight bool) (MediaType, error) {
+	start := st.Position()
+	t, err := Token.Label("media type")(st)
+	if err != nil {
+		return MediaType{}, err
+	}
+	if _, err := parsec.Char('/')(st); err != nil {
+		return MediaType{}, err
+	}
+	s, err := Token.Label("media subtype")(st)
+	if err != nil {
+		return MediaType{}, err
+	}
+	m := MediaType{Type: strings.ToLower(t.(string)), Subtype: strings.ToLower(s.(string)), Params: map[string]string{}}
+	if m.Type == "*" && m.Subtype != "*" {
+		return m, st.ErrorAt(start, "Invalid media range %s/%s", m.Type, m.Subtype)
+	}
+	m.Span = parsec.Span{Start: start, End: st.Position()}
+	if err := parameters(st, &m, weight); err != nil {
+		return m, err
+	}
+	m.Span.End = st.Position()
+	return m, nil
+}
+
+// MediaTypeParser parses a media type with its parameters, as found in a
+// Content-Type header, and returns a MediaType.
+func MediaTypeParser(st *parsec.ParseState) (interface{}, error) {
+	m, err := mediaType(st, false)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// qvalue parses the value of a q parameter: 0 to 1 with at most three
+// decimal places.
+func qvalue(st *parsec.ParseState) (float64, error) {
+	start := st.Position()
+	x, err := paramValue(st)
+	if err != nil {
+		return 0, err
+	}
+	s := x.(string)
+	valid := len(s) >= 1 && len(s) <= 5 && (s[0] == '0' || s[0] == '1')
+	if valid && len(s) > 1 {
+		valid = s[1] == '.'
+		for _, c := range s[2:] {
+			valid = valid && (s[0] == '0' && c >= '0' && c <= '9' || c == '0')
+		}
+	}
+	if !valid {
+		return 0, st.ErrorAt(start, "Invalid q-value %q", s)
+	}
+	n := int(s[0]-'0') * 1000
+	for i, scale := 2, 100; i < len(s); i, scale = i+1, scale/10 {
+		n += int(s[i]-'0') * scale
+	}
+	return float64(n) / 1000, nil
+}
+
+func acceptRange(st *parsec.ParseState) (AcceptRange, error) {
+	m, err := mediaType(st, true)
+	if err != nil {
+		return AcceptRange{}, err
+	}
+	a := AcceptRange{MediaType: m, Q: 1}
+	if _, err := parsec.Try(ows.Then(semicolon).Then(ows).Then(parsec.StringFold("q=")))(st); err == nil {
+		if a.Q, err = qvalue(st); err != nil {
+			return a, err
+		}
+		ext := MediaType{Params: map[string]string{}}
+		if err := parameters(st, &ext, false); err != nil {
+			return a, err
+		}
+	}
+	return a, nil
+}
+
+func (a AcceptRange) specificity() int {
+	switch {
+	case a.Type == "*":
+		return 0
+	case a.Subtype == "*":
+		return 1
+	case len(a.Params) == 0:
+		return 2
+	}
+	return 3
+}
+
+// AcceptParser parses the value of an Accept header and returns its media
+// ranges as an []AcceptRange in order of preference: by weight, then more
+// specific ranges first, then in the order given. Empty list elements are
+// skipped.
+func AcceptParser(st *parsec.ParseState) (interface{}, error) {
+	ranges := []AcceptRange{}
+	for {
+		ows(st)
+		if _, err := parsec.Char(',')(st); err == nil {
+			continue
+		}
+		if _, err := parsec.Eof(st); err == nil {
+			break
+		}
+		a, err := acceptRange(st)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, a)
+		ows(st)
+		if _, err := parsec.Char(',')(st); err != nil {
+			if _, err := parsec.Eof(st); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].Q != ranges[j].Q {
+			return ranges[i].Q > ranges[j].Q
+		}
+		return ranges[i].specificity() > ranges[j].specificity()
+	})
+	return ranges, nil
+}
+
+var contentType = parsec.Parser(MediaTypeParser).Bind(func(m interface{}) parsec.Parser {
+	return ows.Then(parsec.Eof).Then(parsec.Return(m))
+})
+
+// ParseMediaType parses a Content-Type header value.
+func ParseMediaType(s string) (MediaType, error) {
+	x, err := contentType.Parse(s)
+	if err != nil {
+		return MediaType{}, err
+	}
+	return x.(MediaType), nil
+}
+
+// ParseAccept parses an Accept header value.
+func ParseAccept(s string) ([]AcceptRange, error) {
+	x, err := parsec.Parser(AcceptParser).Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	return x.([]AcceptRange), nil
+}
@@ -0,0 +1,125 @@
+package parsec
+
+import (
+	"errors"
+	"sort"
+)
+
+// Edit replaces OldLen bytes at Offset with Text.
+type Edit struct {
+	Offset int
+	OldLen int
+	Text   string
+}
+
+func (e Edit) apply(source string) string {
+	return source[:e.Offset] + e.Text + source[e.Offset+e.OldLen:]
+}
+
+// Incremental keeps a document parsed as a series of items, the way
+// ParseSeq parses it, and after an edit reparses only from the item the
+// edit touches to the first item ending where an old one did, reusing the
+// old items after that. It is meant for editors reparsing on every
+// keystroke, with grammars whose items, such as statements or lines, parse
+// the same wherever they occur. Wrapping the item parser with Recover
+// keeps an error from failing the whole document.
+type Incremental struct {
+	Source string
+	Items  []Spanned
+	// Shift, if not nil, is applied to the value of every item moved by an
+	// edit, so that positions stored in it can be adjusted by the change
+	// in offset and line. Item spans are adjusted without it.
+	Shift func(x interface{}, offset, lines int) interface{}
+
+	p    Parser
+	opts []ParseOption
+}
+
+// NewIncremental parses source as a series of p.
+// Options that rewrite the input, such as WithNormalizedNewlines, are not
+// supported.
+func NewIncremental(p Parser, source string, opts ...ParseOption) (*Incremental, error) {
+	inc := &Incremental{p: p, opts: opts}
+	items, err := inc.parse(source, Position{Line: 1}, nil, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	inc.Source, inc.Items = source, items
+	return inc, nil
+}
+
+// Apply applies e to the document and reparses the items it affects. If
+// the reparse fails, the document is left as it was.
+func (inc *Incremental) Apply(e Edit) error {
+	if e.Offset < 0 || e.OldLen < 0 || e.Offset+e.OldLen > len(inc.Source) {
+		return errors.New("parsec: edit out of range")
+	}
+	// An item ending at the edit may continue into the inserted text.
+	i := sort.Search(len(inc.Items), func(i int) bool { return inc.Items[i].Span.End.Offset >= e.Offset })
+	start := Position{Line: 1}
+	if i < len(inc.Items) {
+		start = inc.Items[i].Span.Start
+	} else if i > 0 {
+		start = inc.Items[i-1].Span.End
+	}
+	source := e.apply(inc.Source)
+	items, err := inc.parse(source, start, inc.Items[:i:i], e.Offset+e.OldLen, len(e.Text)-e.OldLen)
+	if err != nil {
+		return err
+	}
+	inc.Source, inc.Items = source, items
+	return nil
+}
+
+// parse parses items from start, appending them to prefix, until the end
+// of source or until an item ends at the new position of an old item
+// ending at or after editEnd, after which the old items are taken over.
+func (inc *Incremental) parse(source string, start Position, prefix []Spanned, editEnd, delta int) ([]Spanned, error) {
+	st := ParseState{Source: source, Pos: start.Offset, Line: start.Line}
+	for _, opt := range inc.opts {
+		opt(&st)
+	}
+	if st.inputErr != nil {
+		return nil, st.inputErr
+	}
+	if st.origin != nil {
+		return nil, errors.New("parsec: Incremental does not support options that rewrite the input")
+	}
+	items := prefix
+	old := inc.Items
+	for st.Pos < len(st.Source) {
+		begin := st.Position()
+		x, err := inc.p(&st)
+		if err == nil && st.Pos == begin.Offset {
+			err = st.trap("Parser succeeded without consuming input")
+		}
+		if err != nil {
+			return nil, err
+		}
+		end := st.Position()
+		items = append(items, Spanned{Value: x, Span: Span{Start: begin, End: end}})
+		if end.Offset-delta < editEnd {
+			continue
+		}
+		j := sort.Search(len(old), func(j int) bool { return old[j].Span.End.Offset >= end.Offset-delta })
+		if j < len(old) && old[j].Span.End.Offset == end.Offset-delta {
+			return append(items, inc.shift(old[j+1:], delta, end.Line-old[j].Span.End.Line)...), nil
+		}
+	}
+	return items, nil
+}
+
+func (inc *Incremental) shift(items []Spanned, offset, lines int) []Spanned {
+	shifted := make([]Spanned, len(items))
+	for i, item := range items {
+		item.Span.Start.Offset += offset
+		item.Span.Start.Line += lines
+		item.Span.End.Offset += offset
+		item.Span.End.Line += lines
+		if inc.Shift != nil && (offset != 0 || lines != 0) {
+			item.Value = inc.Shift(item.Value, offset, lines)
+		}
+		shifted[i] = item
+	}
+	return shifted
+}
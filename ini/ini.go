@@ -0,0 +1,183 @@
+// Package ini parses INI configuration files into an ordered structure
+// that remembers where every section and key was defined.
+package ini
+
+import (
+	"strings"
+
+	"parsec"
+)
+
+type Key struct {
+	Name  string
+	Value string
+	Span  parsec.Span // the key's name
+}
+
+type Section struct {
+	// Name holds the dot-separated components of the section name, and is
+	// nil for keys that precede the first section header.
+	Name []string
+	Span parsec.Span // the first header naming the section
+	Keys []Key
+}
+
+// Get returns the value of the named key.
+func (s *Section) Get(name string) (string, bool) {
+	for _, k := range s.Keys {
+		if k.Name == name {
+			return k.Value, true
+		}
+	}
+	return "", false
+}
+
+type File struct {
+	Sections []*Section
+}
+
+// Section returns the section with the given name components, or nil.
+func (f *File) Section(name ...string) *Section {
+	for _, s := range f.Sections {
+		if sameName(s.Name, name) {
+			return s
+		}
+	}
+	return nil
+}
+
+func sameName(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+type header struct {
+	name []string
+	span parsec.Span
+}
+
+var ws = parsec.Spaces
+var comment = parsec.OneOf([]byte(";#")).Then(parsec.SkipMany(parsec.NoneOf([]byte("\r\n"))))
+
+var segment = parsec.DoubleQuoted.Or(parsec.Many1(parsec.NoneOf([]byte("[].\"\r\n"))).ToString().Bind(func(x interface{}) parsec.Parser {
+	if s := strings.TrimSpace(x.(string)); s != "" {
+		return parsec.Return(s)
+	}
+	return parsec.Fail("Expected section name")
+}))
+
+var sectionHeader = segment.Between(ws, ws).SepBy1(parsec.Char('.')).Between(parsec.Char('['), parsec.Char(']').Label("']'")).WithSpan().Bind(func(x interface{}) parsec.Parser {
+	s := x.(parsec.Spanned)
+	var name []string
+	for _, seg := range s.Value.([]interface{}) {
+		name = append(name, seg.(string))
+	}
+	return parsec.Return(header{name, s.Span})
+})
+
+// key parses a key name, which may contain inner spaces.
+func key(st *parsec.ParseState) (interface{}, error) {
+	n := strings.IndexAny(st.Source[st.Pos:], "=:;#[\r\n")
+	if n < 0 {
+		n = len(st.Source) - st.Pos
+	}
+	name := strings.TrimRight(st.Source[st.Pos:st.Pos+n], " \t")
+	if name == "" {
+		return parsec.Fail("Expected key")(st)
+	}
+	start := st.Position()
+	st.Pos += len(name)
+	return Key{Name: name, Span: parsec.Span{Start: start, End: st.Position()}}, nil
+}
+
+// bare parses an unquoted value, which ends at a comment preceded by
+// whitespace or at the end of the line. A backslash at the end of a line
+// continues the value on the next, without its leading whitespace.
+func bare(st *parsec.ParseState) (interface{}, error) {
+	var sb strings.Builder
+	for st.Pos < len(st.Source) {
+		c := st.Source[st.Pos]
+		if c == '\r' || c == '\n' {
+			break
+		}
+		if (c == ';' || c == '#') && (sb.Len() == 0 || st.Source[st.Pos-1] == ' ' || st.Source[st.Pos-1] == '\t') {
+			break
+		}
+		if c == '\\' && st.Pos+1 < len(st.Source) && (st.Source[st.Pos+1] == '\n' || st.Source[st.Pos+1] == '\r') {
+			st.Pos++
+			parsec.Newline(st)
+			ws(st)
+			continue
+		}
+		sb.WriteByte(c)
+		st.Pos++
+	}
+	return strings.TrimRight(sb.String(), " \t"), nil
+}
+
+var value = parsec.DoubleQuoted.Or(parsec.RawString("'", "'")).Or(bare)
+
+var pair = parsec.Parser(key).Bind(func(k interface{}) parsec.Parser {
+	return ws.Then(parsec.OneOf([]byte("=:")).Label("'=' or ':'")).Then(ws).Then(value).Bind(func(v interface{}) parsec.Parser {
+		k := k.(Key)
+		k.Value = v.(string)
+		return parsec.Return(k)
+	})
+})
+
+func notEOF(st *parsec.ParseState) (interface{}, error) {
+	if st.Pos < len(st.Source) {
+		return nil, nil
+	}
+	return parsec.Fail("Unexpected end of file")(st)
+}
+
+var line = parsec.Parser(notEOF).Then(ws).Then(sectionHeader.Or(pair).Or(parsec.Return(nil))).Bind(func(x interface{}) parsec.Parser {
+	return ws.Then(parsec.Skip(comment)).Then(parsec.Eol.Label("end of line")).Then(parsec.Return(x))
+})
+
+// Grammar parses a complete INI file into a *File. Sections named more
+// than once are merged; a key defined twice in a section is an error.
+var Grammar = parsec.Many(line).Bind(func(x interface{}) parsec.Parser {
+	return parsec.Parser(parsec.Eof).Then(func(st *parsec.ParseState) (interface{}, error) {
+		f := &File{}
+		var current *Section
+		for _, entry := range x.([]interface{}) {
+			switch e := entry.(type) {
+			case header:
+				if current = f.Section(e.name...); current == nil {
+					current = &Section{Name: e.name, Span: e.span}
+					f.Sections = append(f.Sections, current)
+				}
+			case Key:
+				if current == nil {
+					current = &Section{}
+					f.Sections = append(f.Sections, current)
+				}
+				for _, k := range current.Keys {
+					if k.Name == e.Name {
+						return nil, st.ErrorAt(e.Span.Start, "Duplicate key %q, first defined on line %d", e.Name, k.Span.Start.Line)
+					}
+				}
+				current.Keys = append(current.Keys, e)
+			}
+		}
+		return f, nil
+	})
+})
+
+// Parse parses an INI file.
+func Parse(source string) (*File, error) {
+	x, err := Grammar.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	return x.(*File), nil
+}
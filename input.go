@@ -0,0 +1,151 @@
+package parsec
+
+import (
+	"sort"
+	"strings"
+)
+
+// offsetMapping records that, from pos on, offsets in the parsed source
+// correspond to orig + (offset - pos) in the original input.
+type offsetMapping struct {
+	pos, orig int
+}
+
+func (st *ParseState) originOffset(pos int) int {
+	i := sort.Search(len(st.origin), func(i int) bool { return st.origin[i].pos > pos }) - 1
+	if i < 0 {
+		return pos
+	}
+	return st.origin[i].orig + pos - st.origin[i].pos
+}
+
+// SourceMapper builds input rewritten by an option made with WithRewrite,
+// along with the mapping of its offsets back to the text it came from.
+type SourceMapper struct {
+	out    strings.Builder
+	origin []offsetMapping
+}
+
+// Emit appends text, produced from the input starting at offset orig.
+func (m *SourceMapper) Emit(text string, orig int) {
+	pos := m.out.Len()
+	delta := 0
+	if n := len(m.origin); n > 0 {
+		delta = m.origin[n-1].pos - m.origin[n-1].orig
+	}
+	if pos-orig != delta {
+		m.origin = append(m.origin, offsetMapping{pos: pos, orig: orig})
+	}
+	m.out.WriteString(text)
+}
+
+// outputOffset returns the offset in the output of the input at offset
+// orig, or of the text following it if it was rewritten to something
+// shorter.
+func (m *SourceMapper) outputOffset(orig int) int {
+	i := sort.Search(len(m.origin), func(i int) bool { return m.origin[i].orig > orig })
+	pos := orig
+	if i > 0 {
+		pos = m.origin[i-1].pos + orig - m.origin[i-1].orig
+	}
+	if i < len(m.origin) {
+		pos = min(pos, m.origin[i].pos)
+	}
+	return min(pos, m.out.Len())
+}
+
+// rewrite replaces the unparsed input with the mapper's output, composing
+// its offset mappings with any the state already had: those of the mapper
+// are carried back through the state's, and the state's that fall in the
+// rewritten input are carried forward through the mapper's.
+func (st *ParseState) rewrite(m *SourceMapper) {
+	mapped := make([]offsetMapping, 0, len(m.origin)+1)
+	mapped = append(mapped, offsetMapping{pos: 0, orig: st.originOffset(st.Pos)})
+	for _, om := range m.origin {
+		mapped = append(mapped, offsetMapping{pos: om.pos, orig: st.originOffset(st.Pos + om.orig)})
+	}
+	var carried []offsetMapping
+	for _, om := range st.origin {
+		if om.pos > st.Pos {
+			carried = append(carried, offsetMapping{pos: m.outputOffset(om.pos - st.Pos), orig: om.orig})
+		}
+	}
+	// Merge the two, preferring the mapper's where both have a breakpoint.
+	origin := make([]offsetMapping, 0, len(mapped)+len(carried))
+	for i, j := 0, 0; i < len(mapped) || j < len(carried); {
+		switch {
+		case j == len(carried) || i < len(mapped) && mapped[i].pos < carried[j].pos:
+			origin = append(origin, mapped[i])
+			i++
+		case i < len(mapped) && mapped[i].pos == carried[j].pos:
+			j++
+		default:
+			origin = append(origin, carried[j])
+			j++
+		}
+	}
+	st.Source, st.Pos, st.origin = m.out.String(), 0, origin
+}
+
+// WithRewrite transforms the input before parsing, for options such as
+// those of package textinput: f writes a rewritten form of the unparsed
+// input src to m, and errors and positions still refer to the original
+// text. If f fails with a ParseErr, its Offset is taken to be relative to
+// src.
+func WithRewrite(f func(src string, m *SourceMapper) error) ParseOption {
+	return func(st *ParseState) {
+		var m SourceMapper
+		if err := f(st.Source[st.Pos:], &m); err != nil {
+			if pe, ok := err.(ParseErr); ok {
+				st.Pos += pe.Offset
+				err = st.trap("%s", pe.Reason)
+			}
+			st.inputErr = err
+			return
+		}
+		st.rewrite(&m)
+	}
+}
+
+// WithBOM skips a UTF-8 byte order mark at the start of the input. Input
+// starting with a UTF-16 byte order mark is rejected, since the grammar
+// would otherwise fail at the first byte with a confusing error.
+func WithBOM() ParseOption {
+	return func(st *ParseState) {
+		switch {
+		case strings.HasPrefix(st.Source[st.Pos:], "\xef\xbb\xbf"):
+			st.Pos += 3
+		case strings.HasPrefix(st.Source[st.Pos:], "\xfe\xff"):
+			st.inputErr = st.trap("Unsupported UTF-16 (big-endian) byte order mark")
+		case strings.HasPrefix(st.Source[st.Pos:], "\xff\xfe"):
+			st.inputErr = st.trap("Unsupported UTF-16 (little-endian) byte order mark")
+		}
+	}
+}
+
+// WithNormalizedNewlines rewrites "\r\n" and lone "\r" line endings to "\n"
+// before parsing, so grammars only have to deal with "\n". Error offsets
+// still refer to the original text.
+func WithNormalizedNewlines() ParseOption {
+	return func(st *ParseState) {
+		src := st.Source[st.Pos:]
+		if strings.IndexByte(src, '\r') < 0 {
+			return
+		}
+		var m SourceMapper
+		for i := 0; i < len(src); {
+			j := strings.IndexByte(src[i:], '\r')
+			if j < 0 {
+				m.Emit(src[i:], i)
+				break
+			}
+			m.Emit(src[i:i+j], i)
+			m.Emit("\n", i+j)
+			i += j + 1
+			if i < len(src) && src[i] == '\n' {
+				i++
+			}
+		}
+		st.rewrite(&m)
+	}
+}
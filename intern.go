@@ -0,0 +1,50 @@
+package parsec
+
+import "strings"
+
+// WithInterning makes ToString, TakeWhile and Intern return one shared
+// copy of each distinct string during the parse, for documents repeating
+// the same keys or identifiers many times. The copies do not refer to the
+// input, so they do not keep it alive either.
+func WithInterning() ParseOption {
+	return func(st *ParseState) {
+		st.interned = map[string]string{}
+	}
+}
+
+// Intern returns the shared copy of s if the parse was started
+// WithInterning, and s itself otherwise, for parsers that build token
+// strings of their own.
+func (st *ParseState) Intern(s string) string {
+	if st.interned == nil {
+		return s
+	}
+	if t, ok := st.interned[s]; ok {
+		return t
+	}
+	t := strings.Clone(s)
+	st.interned[t] = t
+	return t
+}
+
+func (st *ParseState) internBytes(b []byte) string {
+	if st.interned == nil {
+		return string(b)
+	}
+	if t, ok := st.interned[string(b)]; ok {
+		return t
+	}
+	t := string(b)
+	st.interned[t] = t
+	return t
+}
+
+// TakeWhile parses the bytes satisfying pred, possibly none, and returns
+// them as a string.
+func TakeWhile(pred func(byte) bool) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		begin := st.Pos
+		st.skipWhile(pred)
+		return st.Intern(st.Source[begin:st.Pos]), nil
+	}
+}
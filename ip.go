@@ -0,0 +1,171 @@
+package parsec
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+func (st *ParseState) ipv4Octets() ([4]byte, error) {
+	var octets [4]byte
+	for i := range octets {
+		if i > 0 {
+			if err := st.expectByte('.', "in IPv4 address"); err != nil {
+				return octets, err
+			}
+		}
+		pos, begin := st.Position(), st.Pos
+		if st.skipWhile(isDigit) == 0 {
+			return octets, st.trap("Expected IPv4 octet")
+		}
+		text := st.Source[begin:st.Pos]
+		if len(text) > 1 && text[0] == '0' {
+			return octets, st.trapAt(pos, "Leading zero in IPv4 octet %s", text)
+		}
+		v := 0
+		for _, c := range []byte(text) {
+			if v = v*10 + int(c-'0'); v > 255 {
+				return octets, st.trapAt(pos, "IPv4 octet %s out of range", text)
+			}
+		}
+		octets[i] = byte(v)
+	}
+	return octets, nil
+}
+
+// IPv4 parses a dotted-quad IPv4 address and returns it as a net.IP.
+func IPv4(st *ParseState) (interface{}, error) {
+	if st.atEnd(st.Pos) || !isDigit(st.Source[st.Pos]) {
+		return nil, st.trap("Expected IPv4 address")
+	}
+	o, err := st.ipv4Octets()
+	if err != nil {
+		return nil, err
+	}
+	return net.IPv4(o[0], o[1], o[2], o[3]), nil
+}
+
+// IPv6 parses an IPv6 address, including "::" compression and a trailing
+// embedded IPv4 address, and returns it as a net.IP.
+func IPv6(st *ParseState) (interface{}, error) {
+	rest := st.peek(2)
+	if len(rest) == 0 || hexValue(rest[0]) < 0 && rest[0] != ':' {
+		return nil, st.trap("Expected IPv6 address")
+	}
+	var groups []uint16
+	ellipsis := -1
+	if len(rest) > 1 && rest[0] == ':' && rest[1] == ':' {
+		ellipsis = 0
+		st.Pos += 2
+	}
+	for len(groups) < 8 {
+		if st.atEnd(st.Pos) || hexValue(st.Source[st.Pos]) < 0 {
+			if ellipsis == len(groups) {
+				break
+			}
+			return nil, st.trap("Expected IPv6 hextet")
+		}
+		if st.embeddedIPv4() {
+			if len(groups) > 6 {
+				return nil, st.trap("Too many hextets before embedded IPv4 address")
+			}
+			o, err := st.ipv4Octets()
+			if err != nil {
+				return nil, err
+			}
+			groups = append(groups, uint16(o[0])<<8|uint16(o[1]), uint16(o[2])<<8|uint16(o[3]))
+			break
+		}
+		pos := st.Position()
+		var v uint16
+		n := 0
+		for ; !st.atEnd(st.Pos) && hexValue(st.Source[st.Pos]) >= 0; st.Pos++ {
+			if n++; n > 4 {
+				return nil, st.trapAt(pos, "IPv6 hextet longer than 4 digits")
+			}
+			v = v<<4 | uint16(hexValue(st.Source[st.Pos]))
+		}
+		groups = append(groups, v)
+		rest := st.peek(2)
+		if len(rest) > 1 && rest[0] == ':' && rest[1] == ':' {
+			if ellipsis >= 0 {
+				return nil, st.trap("Multiple '::' in IPv6 address")
+			}
+			ellipsis = len(groups)
+			st.Pos += 2
+		} else if len(rest) > 0 && rest[0] == ':' && len(groups) < 8 {
+			st.Pos++
+		} else {
+			break
+		}
+	}
+	switch {
+	case ellipsis < 0 && len(groups) != 8:
+		return nil, st.trap("IPv6 address must have 8 hextets")
+	case ellipsis >= 0 && len(groups) > 7:
+		return nil, st.trap("Too many hextets in IPv6 address with '::'")
+	}
+	ip := make(net.IP, net.IPv6len)
+	for i, g := range groups {
+		j := i
+		if ellipsis >= 0 && i >= ellipsis {
+			j += 8 - len(groups)
+		}
+		ip[2*j], ip[2*j+1] = byte(g>>8), byte(g)
+	}
+	return ip, nil
+}
+
+// embeddedIPv4 reports whether the input continues with digits followed by
+// a '.', which can only be the start of an IPv4 address.
+func (st *ParseState) embeddedIPv4() bool {
+	i := st.Pos
+	for !st.atEnd(i) && isDigit(st.Source[i]) {
+		i++
+	}
+	return i > st.Pos && !st.atEnd(i) && st.Source[i] == '.'
+}
+
+// IPAddr parses either an IPv4 or an IPv6 address.
+func IPAddr(st *ParseState) (interface{}, error) {
+	for i := st.Pos; !st.atEnd(i); i++ {
+		switch c := st.Source[i]; {
+		case c == ':':
+			return IPv6(st)
+		case c == '.':
+			return IPv4(st)
+		case hexValue(c) < 0:
+			return nil, st.trap("Expected IP address")
+		}
+	}
+	return nil, st.trap("Expected IP address")
+}
+
+// CIDR parses an address and prefix length such as 10.0.0.0/8 or
+// 2001:db8::/32 and returns the network as a *net.IPNet, as
+// net.ParseCIDR does.
+func CIDR(st *ParseState) (interface{}, error) {
+	begin := st.Pos
+	x, err := IPAddr(st)
+	if err != nil {
+		return nil, err
+	}
+	ip, bits := x.(net.IP), 8*net.IPv6len
+	if !strings.Contains(st.Source[begin:st.Pos], ":") {
+		ip, bits = ip.To4(), 8*net.IPv4len
+	}
+	if err := st.expectByte('/', "before prefix length"); err != nil {
+		return nil, err
+	}
+	pos, digits := st.Position(), st.Pos
+	if st.skipWhile(isDigit) == 0 {
+		return nil, st.trap("Expected prefix length")
+	}
+	text := st.Source[digits:st.Pos]
+	n, err := strconv.Atoi(text)
+	if err != nil || n > bits || len(text) > 1 && text[0] == '0' {
+		return nil, st.trapAt(pos, "Invalid prefix length /%s for %d-bit address", text, bits)
+	}
+	mask := net.CIDRMask(n, bits)
+	return &net.IPNet{IP: ip.Mask(mask), Mask: mask}, nil
+}
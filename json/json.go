@@ -0,0 +1,169 @@
+// Package json is a JSON grammar built from parsec combinators. Objects
+// decode to map[string]interface{}, arrays to []interface{}, numbers to
+// float64 and strings, booleans and null to their Go equivalents.
+package json
+
+import (
+	"parsec"
+)
+
+// Options configures Grammar.
+type Options struct {
+	// Positions wraps every value in a parsec.Spanned recording where it
+	// appeared in the input.
+	Positions bool
+
+	// The remaining options accept relaxed dialects such as JSONC and
+	// JSON5, usually found in configuration files.
+
+	// Comments permits // line comments and /* block comments */ wherever
+	// whitespace is allowed.
+	Comments bool
+	// TrailingCommas permits a comma after the last array element or
+	// object member.
+	TrailingCommas bool
+	// SingleQuotes permits strings delimited by single quotes, and as in
+	// JSON5 unescaped control characters other than newlines in strings.
+	SingleQuotes bool
+	// UnquotedKeys permits object keys written as identifiers.
+	UnquotedKeys bool
+}
+
+var jsonString = parsec.QuoteOptions{
+	Quotes: `"`,
+	Escape: '\\',
+	Escapes: map[rune]string{
+		'/': "/", 'b': "\b", 'f': "\f", 'n': "\n", 'r': "\r", 't': "\t",
+	},
+	UTF16Escapes: true,
+	NoControl:    true,
+}
+
+type member struct {
+	key   string
+	value interface{}
+}
+
+// Grammar returns a parser for a single JSON value. It does not skip
+// leading whitespace, but consumes whitespace following the value.
+func Grammar(opts Options) parsec.Parser {
+	ws := whitespace(opts)
+	sepBy := parsec.Parser.SepBy
+	if opts.TrailingCommas {
+		sepBy = parsec.Parser.SepEndBy
+	}
+	lexeme := func(p parsec.Parser) parsec.Parser {
+		return p.Bind(func(x interface{}) parsec.Parser {
+			return ws.Then(parsec.Return(x))
+		})
+	}
+	sym := func(c byte) parsec.Parser {
+		return lexeme(parsec.Char(c))
+	}
+
+	var value parsec.Parser
+	valueRef := parsec.Parser(func(st *parsec.ParseState) (interface{}, error) {
+		return value(st)
+	})
+
+	strOpts := jsonString
+	if opts.SingleQuotes {
+		strOpts.Quotes = `"'`
+		strOpts.NoControl = false
+	}
+	str := parsec.QuotedString(strOpts)
+	key := str
+	if opts.UnquotedKeys {
+		key = str.Or(identifier)
+	}
+	pair := lexeme(key).Bind(func(k interface{}) parsec.Parser {
+		return sym(':').Then(valueRef).Bind(func(v interface{}) parsec.Parser {
+			return parsec.Return(member{k.(string), v})
+		})
+	}).Label("object member")
+	object := sepBy(pair, sym(',')).Between(sym('{'), parsec.Char('}')).Bind(func(x interface{}) parsec.Parser {
+		obj := make(map[string]interface{})
+		for _, m := range x.([]interface{}) {
+			obj[m.(member).key] = m.(member).value
+		}
+		return parsec.Return(obj)
+	})
+	array := sepBy(valueRef, sym(',')).Between(sym('['), parsec.Char(']'))
+	literal := func(s string, v interface{}) parsec.Parser {
+		return parsec.String(s).Then(parsec.Return(v))
+	}
+
+	raw := parsec.Either(object, array).
+		Or(str).
+		Or(number).
+		Or(literal("true", true)).
+		Or(literal("false", false)).
+		Or(literal("null", nil)).
+		Label("value")
+	if opts.Positions {
+		raw = raw.WithSpan()
+	}
+	value = lexeme(raw)
+	return value
+}
+
+func whitespace(opts Options) parsec.Parser {
+	space := parsec.OneOf([]byte(" \t\r\n"))
+	if opts.Comments {
+		lineComment := parsec.String("//").Then(parsec.SkipMany(parsec.NoneOf([]byte("\n"))))
+		blockComment := parsec.String("/*").Then(parsec.Skip(parsec.ManyTill(parsec.AnyChar, parsec.String("*/"))))
+		space = space.Or(lineComment).Or(blockComment)
+	}
+	return parsec.SkipMany(space)
+}
+
+func isIdentStart(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '_' || c == '$'
+}
+
+// identifier parses an unquoted object key.
+func identifier(st *parsec.ParseState) (interface{}, error) {
+	begin := st.Pos
+	if st.Pos >= len(st.Source) || !isIdentStart(st.Source[st.Pos]) {
+		return parsec.Fail("Expected identifier")(st)
+	}
+	for st.Pos++; st.Pos < len(st.Source) && (isIdentStart(st.Source[st.Pos]) || '0' <= st.Source[st.Pos] && st.Source[st.Pos] <= '9'); st.Pos++ {
+	}
+	return st.Source[begin:st.Pos], nil
+}
+
+// number parses a JSON number, which unlike parsec.Float has no leading
+// '+', no leading zeros and needs digits before a fraction.
+func number(st *parsec.ParseState) (interface{}, error) {
+	start := st.Position()
+	if st.Pos >= len(st.Source) || st.Source[st.Pos] != '-' && (st.Source[st.Pos] < '0' || st.Source[st.Pos] > '9') {
+		return parsec.Fail("Expected number")(st)
+	}
+	x, err := parsec.Float(st)
+	if err != nil {
+		return nil, err
+	}
+	text := x.(parsec.FloatLit).Text
+	if text[0] == '-' {
+		text = text[1:]
+	}
+	if text == "" || text[0] < '0' || text[0] > '9' || len(text) > 1 && text[0] == '0' && text[1] >= '0' && text[1] <= '9' {
+		return nil, st.ErrorAt(start, "Invalid number %s", x.(parsec.FloatLit).Text)
+	}
+	return x.(parsec.FloatLit).Value, nil
+}
+
+// Document returns a parser for a complete JSON document: a value with
+// optional surrounding whitespace, followed by the end of input.
+func Document(opts Options) parsec.Parser {
+	return whitespace(opts).Then(Grammar(opts)).Bind(func(x interface{}) parsec.Parser {
+		return parsec.Parser(parsec.Eof).Then(parsec.Return(x))
+	})
+}
+
+var document = Document(Options{})
+
+// Parse parses a complete JSON document.
+func Parse(source string) (interface{}, error) {
+	return document.Parse(source)
+}
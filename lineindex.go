@@ -0,0 +1,78 @@
+package parsec
+
+import "sort"
+
+// LineCol is a line and column, both counted from 1, with columns in bytes.
+type LineCol struct {
+	Line int `json:"line"`
+	Col  int `json:"col"`
+}
+
+// LineIndex records where the lines of a source text start, to convert
+// between offsets and lines and columns without rescanning the text. Lines
+// end as they do for ParseState.Line: at "\n", "\r\n" or a lone "\r".
+type LineIndex struct {
+	source string
+	starts []int
+}
+
+func NewLineIndex(source string) *LineIndex {
+	ix := &LineIndex{source: source, starts: []int{0}}
+	for i := 0; i < len(source); i++ {
+		if c := source[i]; c == '\n' || c == '\r' && (i+1 == len(source) || source[i+1] != '\n') {
+			ix.starts = append(ix.starts, i+1)
+		}
+	}
+	return ix
+}
+
+// Lines returns the number of lines, counting the empty line after a
+// final line terminator.
+func (ix *LineIndex) Lines() int {
+	return len(ix.starts)
+}
+
+// LineStart returns the offset at which line starts.
+func (ix *LineIndex) LineStart(line int) int {
+	return ix.starts[line-1]
+}
+
+// LineEnd returns the offset of the terminator ending line, or the end of
+// the source for the last line.
+func (ix *LineIndex) LineEnd(line int) int {
+	if line == len(ix.starts) {
+		return len(ix.source)
+	}
+	end := ix.starts[line] - 1
+	if end > 0 && ix.source[end] == '\n' && ix.source[end-1] == '\r' {
+		end--
+	}
+	return end
+}
+
+// PosFor returns the line and column of offset, which is clamped to the
+// source.
+func (ix *LineIndex) PosFor(offset int) LineCol {
+	offset = max(0, min(offset, len(ix.source)))
+	line := sort.Search(len(ix.starts), func(i int) bool { return ix.starts[i] > offset })
+	return LineCol{Line: line, Col: offset - ix.starts[line-1] + 1}
+}
+
+// Position returns the Position of offset, as ParseState.Position would
+// give it there.
+func (ix *LineIndex) Position(offset int) Position {
+	return Position{Offset: offset, Line: ix.PosFor(offset).Line}
+}
+
+// OffsetFor returns the offset of a line and column. It fails if the line
+// does not exist or the column lies past the line's end.
+func (ix *LineIndex) OffsetFor(line, col int) (int, bool) {
+	if line < 1 || line > len(ix.starts) || col < 1 {
+		return 0, false
+	}
+	offset := ix.starts[line-1] + col - 1
+	if offset > ix.LineEnd(line) {
+		return 0, false
+	}
+	return offset, true
+}
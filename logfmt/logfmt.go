@@ -0,0 +1,136 @@
+// Package logfmt parses logfmt lines such as
+//
+//	level=info msg="request done" path=/api duration=12ms cached
+//
+// into key/value pairs. Keys and unquoted values are substrings of the
+// input rather than copies, so that large volumes of logs can be scanned
+// cheaply.
+package logfmt
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"parsec"
+)
+
+type Pair struct {
+	Key   string
+	Value string
+	// Flag is set for a key that appears without a value.
+	Flag bool
+	Span parsec.Span
+}
+
+var ws = parsec.Spaces
+
+// token parses a run of printable characters other than those in stop.
+func token(stop, what string) parsec.Parser {
+	return func(st *parsec.ParseState) (interface{}, error) {
+		n := strings.IndexFunc(st.Source[st.Pos:], func(r rune) bool {
+			return r <= ' ' || r == 0x7f || strings.ContainsRune(stop, r)
+		})
+		if n < 0 {
+			n = len(st.Source) - st.Pos
+		}
+		if n == 0 {
+			return parsec.Fail("Expected " + what)(st)
+		}
+		s := st.Source[st.Pos : st.Pos+n]
+		st.Pos += n
+		return s, nil
+	}
+}
+
+var quoted = parsec.QuotedString(parsec.QuoteOptions{
+	Quotes:       `"`,
+	Escape:       '\\',
+	Escapes:      map[rune]string{'n': "\n", 'r': "\r", 't': "\t"},
+	UTF16Escapes: true,
+})
+
+var value = quoted.Or(token(`"`, "value")).Or(parsec.Return(""))
+
+var pair = token(`="`, "key").Bind(func(k interface{}) parsec.Parser {
+	return parsec.Char('=').Then(value).Bind(func(v interface{}) parsec.Parser {
+		return parsec.Return(Pair{Key: k.(string), Value: v.(string)})
+	}).Or(parsec.Return(Pair{Key: k.(string), Flag: true}))
+}).WithSpan().Bind(func(x interface{}) parsec.Parser {
+	s := x.(parsec.Spanned)
+	p := s.Value.(Pair)
+	p.Span = s.Span
+	return ws.Then(parsec.Return(p))
+})
+
+// Line parses one line, including its line terminator, and returns its
+// pairs as a []Pair.
+var Line = ws.Then(parsec.Many(pair)).Bind(func(x interface{}) parsec.Parser {
+	pairs := make([]Pair, len(x.([]interface{})))
+	for i, p := range x.([]interface{}) {
+		pairs[i] = p.(Pair)
+	}
+	return parsec.Eol.Label("key").Then(parsec.Return(pairs))
+})
+
+// ParseLine parses a single line.
+func ParseLine(line string) ([]Pair, error) {
+	x, err := Line.Parse(line)
+	if err != nil {
+		return nil, err
+	}
+	return x.([]Pair), nil
+}
+
+// Reader reads logfmt lines one at a time from an io.Reader.
+type Reader struct {
+	r      *bufio.Reader
+	offset int
+	line   int
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r), line: 1}
+}
+
+// Read returns the pairs of the next non-blank line, or io.EOF at the end
+// of input. Positions in the pairs and in errors are relative to the whole
+// input.
+func (r *Reader) Read() ([]Pair, error) {
+	for {
+		text, err := r.r.ReadString('\n')
+		if err == io.EOF && text != "" {
+			err = nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		st := parsec.ParseState{Source: text, Line: 1}
+		x, err := Line(&st)
+		if err != nil {
+			if pe, ok := err.(parsec.ParseErr); ok {
+				pe.Offset += r.offset
+				pe.Line += r.line - 1
+				err = pe
+			}
+			// The bad record is consumed; the next starts after it.
+			r.offset += len(text)
+			r.line += strings.Count(text, "\n")
+			return nil, err
+		}
+		pairs := x.([]Pair)
+		for i := range pairs {
+			pairs[i].Span.Start = r.shift(pairs[i].Span.Start)
+			pairs[i].Span.End = r.shift(pairs[i].Span.End)
+		}
+		r.offset += len(text)
+		r.line += st.Line - 1
+		if len(pairs) > 0 {
+			return pairs, nil
+		}
+	}
+}
+
+func (r *Reader) shift(pos parsec.Position) parsec.Position {
+	return parsec.Position{Offset: pos.Offset + r.offset, Line: pos.Line + r.line - 1}
+}
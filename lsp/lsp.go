@@ -0,0 +1,177 @@
+// Package lsp converts parse errors and spans into the diagnostics of the
+// Language Server Protocol, whose positions are zero-based lines and
+// columns counted in UTF-16 code units. The types marshal to the
+// protocol's JSON.
+package lsp
+
+import (
+	"errors"
+	"unicode/utf8"
+
+	"parsec"
+)
+
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+type Severity int
+
+const (
+	SeverityError       Severity = 1
+	SeverityWarning     Severity = 2
+	SeverityInformation Severity = 3
+	SeverityHint        Severity = 4
+)
+
+type RelatedInformation struct {
+	Location Location `json:"location"`
+	Message  string   `json:"message"`
+}
+
+type Diagnostic struct {
+	Range              Range                `json:"range"`
+	Severity           Severity             `json:"severity,omitempty"`
+	Source             string               `json:"source,omitempty"`
+	Message            string               `json:"message"`
+	RelatedInformation []RelatedInformation `json:"relatedInformation,omitempty"`
+}
+
+// Document converts positions in one source text, given as it was before
+// any input options such as textinput.WithEncoding rewrote it, since that
+// is what ParseErr offsets refer to.
+type Document struct {
+	URI    string
+	Source string
+	// Name is the Source of the diagnostics made, such as the language.
+	Name  string
+	index *parsec.LineIndex
+}
+
+func NewDocument(uri, source string) *Document {
+	return &Document{URI: uri, Source: source, index: parsec.NewLineIndex(source)}
+}
+
+// Position returns the position of a byte offset in the source.
+func (d *Document) Position(offset int) Position {
+	offset = max(0, min(offset, len(d.Source)))
+	line := d.index.PosFor(offset).Line
+	char := 0
+	for _, r := range d.Source[d.index.LineStart(line):offset] {
+		if r >= 0x10000 {
+			char += 2
+		} else {
+			char++
+		}
+	}
+	return Position{Line: line - 1, Character: char}
+}
+
+// Range returns the range of span.
+func (d *Document) Range(span parsec.Span) Range {
+	return Range{Start: d.Position(span.Start.Offset), End: d.Position(span.End.Offset)}
+}
+
+// Related returns an entry for a diagnostic's RelatedInformation, pointing
+// at span in this document.
+func (d *Document) Related(span parsec.Span, message string) RelatedInformation {
+	return RelatedInformation{Location: Location{URI: d.URI, Range: d.Range(span)}, Message: message}
+}
+
+// Diagnostic returns a diagnostic for span, such as that of an ErrorNode or
+// of a construct a linter warns about.
+func (d *Document) Diagnostic(span parsec.Span, severity Severity, message string) Diagnostic {
+	return Diagnostic{Range: d.Range(span), Severity: severity, Source: d.Name, Message: message}
+}
+
+// Diagnostics returns an error diagnostic for each ParseErr in err, which
+// may be a ParseErr, a slice of them such as toml.Errors, or an error
+// joining several, as errors.Join does. A ParseErr marks a single offset,
+// so its range covers the character there, or nothing at the end of a
+// line. Errors without a position yield no diagnostic.
+func (d *Document) Diagnostics(err error) []Diagnostic {
+	var diags []Diagnostic
+	for _, pe := range parseErrs(err) {
+		end := pe.Offset
+		if end < len(d.Source) && d.Source[end] != '\n' && d.Source[end] != '\r' {
+			_, size := utf8.DecodeRuneInString(d.Source[end:])
+			end += size
+		}
+		span := parsec.Span{Start: parsec.Position{Offset: pe.Offset}, End: parsec.Position{Offset: end}}
+		diags = append(diags, d.Diagnostic(span, SeverityError, pe.Reason))
+	}
+	return diags
+}
+
+func parseErrs(err error) []parsec.ParseErr {
+	switch err := err.(type) {
+	case nil:
+		return nil
+	case parsec.ParseErr:
+		return []parsec.ParseErr{err}
+	case interface{ Unwrap() []error }:
+		var errs []parsec.ParseErr
+		for _, e := range err.Unwrap() {
+			errs = append(errs, parseErrs(e)...)
+		}
+		return errs
+	}
+	var pe parsec.ParseErr
+	if errors.As(err, &pe) {
+		return []parsec.ParseErr{pe}
+	}
+	return nil
+}
+
+// SemanticTokens encodes tokens, such as those of a parsec.Highlighter, as
+// the data of an LSP semantic tokens response: five integers per token,
+// giving its line and start relative to the previous token, its length,
+// and the index of its class in legend, with no modifiers. Tokens spanning
+// lines are split at line ends, and tokens whose class is not in legend are
+// left out.
+func (d *Document) SemanticTokens(tokens []parsec.Token, legend []string) []uint32 {
+	index := make(map[string]int, len(legend))
+	for i, class := range legend {
+		index[class] = i
+	}
+	var data []uint32
+	var last Position
+	emit := func(start, end int, class int) {
+		from, to := d.Position(start), d.Position(end)
+		if to.Character <= from.Character {
+			return
+		}
+		char := from.Character
+		if from.Line == last.Line {
+			char -= last.Character
+		}
+		data = append(data, uint32(from.Line-last.Line), uint32(char), uint32(to.Character-from.Character), uint32(class), 0)
+		last = from
+	}
+	for _, t := range tokens {
+		class, ok := index[t.Class]
+		if !ok {
+			continue
+		}
+		start := t.Span.Start.Offset
+		line := d.index.PosFor(start).Line
+		for line < d.index.Lines() && d.index.LineStart(line+1) < t.Span.End.Offset {
+			emit(start, d.index.LineEnd(line), class)
+			line++
+			start = d.index.LineStart(line)
+		}
+		emit(start, t.Span.End.Offset, class)
+	}
+	return data
+}
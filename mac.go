@@ -0,0 +1,51 @@
+package parsec
+
+import (
+	"net"
+)
+
+// MAC parses an EUI-48 or EUI-64 hardware address written with colons
+// (00:1a:2b:3c:4d:5e), hyphens (00-1A-2B-3C-4D-5E) or in Cisco's dotted
+// notation (001a.2b3c.4d5e) and returns it as a net.HardwareAddr.
+func MAC(st *ParseState) (interface{}, error) {
+	rest := st.peek(5)
+	var sep byte
+	var width int
+	switch {
+	case len(rest) > 2 && (rest[2] == ':' || rest[2] == '-'):
+		sep, width = rest[2], 2
+	case len(rest) > 4 && rest[4] == '.':
+		sep, width = '.', 4
+	default:
+		return nil, st.trap("Expected MAC address")
+	}
+	var addr net.HardwareAddr
+	for {
+		for i := 0; i < width; i += 2 {
+			hi, lo := st.hexPair()
+			if hi < 0 || lo < 0 {
+				return nil, st.trap("Expected hex digits in MAC address")
+			}
+			addr = append(addr, byte(hi<<4|lo))
+			st.Pos += 2
+		}
+		if len(addr) == 8 {
+			break
+		}
+		rest := st.peek(2)
+		if len(addr) == 6 && (len(rest) < 2 || rest[0] != sep || hexValue(rest[1]) < 0) {
+			break
+		}
+		if err := st.expectByte(sep, "in MAC address"); err != nil {
+			return nil, err
+		}
+	}
+	return addr, nil
+}
+
+func (st *ParseState) hexPair() (int, int) {
+	if st.atEnd(st.Pos + 1) {
+		return -1, -1
+	}
+	return hexValue(st.Source[st.Pos]), hexValue(st.Source[st.Pos+1])
+}
@@ -0,0 +1,248 @@
+// Package markdown parses Markdown inline constructs: emphasis, strong
+// emphasis, code spans, links, images and autolinks. Delimiters that do
+// not form a construct are taken as literal text, which the grammar finds
+// by backtracking with parsec.Try. Unlike CommonMark, a link whose text is
+// followed by an opening parenthesis must have a well-formed destination:
+// the grammar commits with parsec.Cut at that point and reports an error.
+package markdown
+
+import (
+	"strings"
+
+	"parsec"
+)
+
+type Kind int
+
+const (
+	Text Kind = iota
+	Emphasis
+	Strong
+	Code
+	Link
+	Image
+	Autolink
+)
+
+type Node struct {
+	Kind Kind
+	// Text is the content of text and code nodes.
+	Text string
+	// URL and Title are the destination of links, images and autolinks.
+	URL, Title string
+	// Children holds the content of emphasis, the text of links and the
+	// description of images.
+	Children []*Node
+	Span     parsec.Span
+}
+
+const specials = "*_`[]!<\\"
+
+var text = parsec.Many1(parsec.NoneOf([]byte(specials))).ToString().Bind(textNode)
+
+func textNode(x interface{}) parsec.Parser {
+	switch x := x.(type) {
+	case byte:
+		return parsec.Return(&Node{Kind: Text, Text: string(x)})
+	case rune:
+		return parsec.Return(&Node{Kind: Text, Text: string(x)})
+	}
+	return parsec.Return(&Node{Kind: Text, Text: x.(string)})
+}
+
+// escape parses a backslash followed by ASCII punctuation as that
+// character. Other backslashes are literal.
+var escape = parsec.Try(parsec.Char('\\').Then(parsec.Punctuation)).Bind(textNode)
+
+// codeSpan parses a run of backticks, the text up to a run of the same
+// length and that run. A run without a match is literal text.
+func codeSpan(st *parsec.ParseState) (interface{}, error) {
+	rest := st.Source[st.Pos:]
+	n := len(rest) - len(strings.TrimLeft(rest, "`"))
+	if n == 0 {
+		return parsec.Fail("Expected code span")(st)
+	}
+	fence := rest[:n]
+	end := -1
+	for i := n; i < len(rest); {
+		j := strings.Index(rest[i:], fence)
+		if j < 0 {
+			break
+		}
+		i += j
+		if k := i + n; k == len(rest) || rest[k] != '`' {
+			end = i
+			break
+		}
+		i += n + len(rest[i+n:]) - len(strings.TrimLeft(rest[i+n:], "`"))
+	}
+	if end < 0 {
+		parsec.String(fence)(st)
+		return &Node{Kind: Text, Text: fence}, nil
+	}
+	code := rest[n:end]
+	if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.TrimSpace(code) != "" {
+		code = code[1 : len(code)-1]
+	}
+	parsec.String(rest[:end+n])(st)
+	return &Node{Kind: Code, Text: code}, nil
+}
+
+// opener parses an emphasis delimiter that can open: it is followed by a
+// non-space and, for _, not preceded by a letter or digit.
+func opener(delim string) parsec.Parser {
+	return func(st *parsec.ParseState) (interface{}, error) {
+		rest := st.Source[st.Pos:]
+		if !strings.HasPrefix(rest, delim) || len(rest) == len(delim) || strings.IndexByte(" \t\r\n", rest[len(delim)]) >= 0 {
+			return parsec.Fail("Expected '" + delim + "'")(st)
+		}
+		if delim[0] == '_' && st.Pos > 0 && isAlnum(st.Source[st.Pos-1]) {
+			return parsec.Fail("Expected '" + delim + "'")(st)
+		}
+		return parsec.String(delim)(st)
+	}
+}
+
+func isAlnum(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// notAt fails without consuming input at the delimiter that ends the
+// enclosing construct.
+func notAt(stop string) parsec.Parser {
+	return func(st *parsec.ParseState) (interface{}, error) {
+		if stop != "" && strings.HasPrefix(st.Source[st.Pos:], stop) {
+			return parsec.Fail("Unexpected '" + stop + "'")(st)
+		}
+		return nil, nil
+	}
+}
+
+var inlines = map[string]parsec.Parser{}
+
+// content parses the nodes inside a construct ended by stop.
+func content(stop string) parsec.Parser {
+	return func(st *parsec.ParseState) (interface{}, error) {
+		return inlines[stop](st)
+	}
+}
+
+func emphasis(delim string, kind Kind) parsec.Parser {
+	return parsec.Try(opener(delim).Then(content(delim)).Bind(func(x interface{}) parsec.Parser {
+		return parsec.String(delim).Then(parsec.Return(&Node{Kind: kind, Children: x.([]*Node)}))
+	}))
+}
+
+// destination parses the rest of a link after "](": a URL, an optional
+// quoted title and the closing parenthesis.
+func destination(st *parsec.ParseState) (interface{}, error) {
+	start := st.Position()
+	end := strings.IndexAny(st.Source[st.Pos:], " \t\r\n)")
+	if end < 0 {
+		return nil, st.ErrorAt(start, "Unterminated link destination")
+	}
+	url := st.Source[st.Pos : st.Pos+end]
+	st.Pos += end
+	ws := parsec.SkipMany(parsec.OneOf([]byte(" \t\r\n")))
+	ws(st)
+	title := ""
+	if x, err := parsec.Try(parsec.RawString(`"`, `"`))(st); err == nil {
+		title = x.(string)
+		ws(st)
+	}
+	if _, err := parsec.Char(')')(st); err != nil {
+		return nil, st.ErrorAt(start, "Unterminated link destination")
+	}
+	return [2]string{url, title}, nil
+}
+
+func link(prefix string, kind Kind) parsec.Parser {
+	open := parsec.Try(parsec.String(prefix).Then(content("]").Or(parsec.Return([]*Node{}))).Bind(func(x interface{}) parsec.Parser {
+		return parsec.String("](").Then(parsec.Return(x))
+	}))
+	return open.Bind(func(x interface{}) parsec.Parser {
+		return parsec.Cut(destination).Bind(func(y interface{}) parsec.Parser {
+			d := y.([2]string)
+			return parsec.Return(&Node{Kind: kind, URL: d[0], Title: d[1], Children: x.([]*Node)})
+		})
+	})
+}
+
+// autolink parses <scheme:...>.
+func autolink(st *parsec.ParseState) (interface{}, error) {
+	rest := st.Source[st.Pos:]
+	if len(rest) == 0 || rest[0] != '<' {
+		return parsec.Fail("Expected autolink")(st)
+	}
+	end := strings.IndexAny(rest[1:], " \t\r\n<>") + 1
+	if end == 0 || rest[end] != '>' {
+		return parsec.Fail("Expected autolink")(st)
+	}
+	url := rest[1:end]
+	colon := strings.IndexByte(url, ':')
+	if colon < 2 || colon > 32 || !isLetter(url[0]) || strings.IndexFunc(url[:colon], func(r rune) bool {
+		return !(r < 0x80 && (isAlnum(byte(r)) || r == '+' || r == '.' || r == '-'))
+	}) >= 0 {
+		return parsec.Fail("Expected autolink")(st)
+	}
+	st.Pos += end + 1
+	return &Node{Kind: Autolink, URL: url}, nil
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// element returns a parser for one inline node inside a construct ended by
+// stop. Any special character that starts no construct is literal text.
+func element(stop string) parsec.Parser {
+	return parsec.Parser(codeSpan).
+		Or(emphasis("**", Strong)).
+		Or(emphasis("__", Strong)).
+		Or(notAt(stop).Then(emphasis("*", Emphasis).
+			Or(emphasis("_", Emphasis)).
+			Or(link("![", Image)).
+			Or(link("[", Link)).
+			Or(autolink).
+			Or(escape).
+			Or(text).
+			Or(parsec.Parser(parsec.AnyChar).Bind(textNode)))).
+		WithSpan().Bind(func(x interface{}) parsec.Parser {
+		s := x.(parsec.Spanned)
+		n := s.Value.(*Node)
+		n.Span = s.Span
+		return parsec.Return(n)
+	})
+}
+
+// merge joins adjacent text nodes.
+func merge(x interface{}) parsec.Parser {
+	var nodes []*Node
+	for _, n := range x.([]interface{}) {
+		n := n.(*Node)
+		if last := len(nodes) - 1; last >= 0 && n.Kind == Text && nodes[last].Kind == Text {
+			nodes[last] = &Node{Kind: Text, Text: nodes[last].Text + n.Text, Span: parsec.Span{Start: nodes[last].Span.Start, End: n.Span.End}}
+			continue
+		}
+		nodes = append(nodes, n)
+	}
+	return parsec.Return(nodes)
+}
+
+func init() {
+	for _, stop := range []string{"**", "__", "*", "_", "]"} {
+		inlines[stop] = parsec.Many1(element(stop)).Bind(merge)
+	}
+	inlines[""] = parsec.Many(element("")).Bind(merge)
+}
+
+// Parse parses the inline content of a paragraph.
+func Parse(source string) ([]*Node, error) {
+	x, err := inlines[""].Bind(func(x interface{}) parsec.Parser {
+		return parsec.Parser(parsec.Eof).Then(parsec.Return(x))
+	}).Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	return x.([]*Node), nil
+}
@@ -0,0 +1,42 @@
+package parsec
+
+// Counter is satisfied by *expvar.Int and most metrics libraries' counters.
+type Counter interface {
+	Add(delta int64)
+}
+
+// Metrics holds the counters updated by a Metered parser. Nil counters are
+// ignored. InFlight is used as a gauge: it is incremented when a parse starts
+// and decremented when it ends.
+type Metrics struct {
+	Documents  Counter
+	Bytes      Counter
+	Errors     Counter
+	Recoveries Counter
+	InFlight   Counter
+}
+
+func (m *Metrics) add(c Counter, delta int64) {
+	if c != nil && delta != 0 {
+		c.Add(delta)
+	}
+}
+
+// Metered returns a parser that behaves like p and reports each run to m,
+// so a top-level grammar can be instrumented once instead of at every call
+// site.
+func (p Parser) Metered(m *Metrics) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		start, recoveries := st.Pos, st.recoveries
+		m.add(m.InFlight, 1)
+		defer m.add(m.InFlight, -1)
+		x, err := p(st)
+		m.add(m.Documents, 1)
+		m.add(m.Bytes, int64(st.Pos-start))
+		m.add(m.Recoveries, int64(st.recoveries-recoveries))
+		if err != nil {
+			m.add(m.Errors, 1)
+		}
+		return x, err
+	}
+}
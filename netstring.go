@@ -0,0 +1,62 @@
+package parsec
+
+// Netstring parses a netstring such as 5:hello, and returns its payload as
+// a string sharing the input's memory. Run through a Feeder, a netstring
+// longer than the input received so far waits for more rather than
+// failing.
+var Netstring = NetstringMax(-1)
+
+// NetstringMax is Netstring rejecting payloads longer than max bytes as
+// soon as the length is read, so that a stream cannot make its reader
+// buffer an arbitrary amount of input. A negative max means no limit.
+func NetstringMax(max int) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		start, begin := st.Position(), st.Pos
+		first, ok := st.next(isDigit)
+		if !ok {
+			return nil, st.trap("Expected netstring length")
+		}
+		n := int(first - '0')
+		for {
+			// Check the limit as digits arrive, so a Feeder does not wait
+			// for the rest of a length that is already too large.
+			if max >= 0 && n > max {
+				return nil, st.trapAt(start, "Netstring length %s exceeds maximum of %d", st.Source[begin:st.Pos], max)
+			}
+			c, ok := st.next(isDigit)
+			if !ok {
+				break
+			}
+			if first == '0' {
+				return nil, st.trapAt(start, "Leading zero in netstring length")
+			}
+			if n > (1<<31-1)/10 {
+				return nil, st.trapAt(start, "Netstring length %s... too large", st.Source[begin:st.Pos])
+			}
+			n = n*10 + int(c-'0')
+		}
+		if err := st.expectByte(':', "after netstring length"); err != nil {
+			return nil, err
+		}
+		payload, ok := st.take(n)
+		if !ok {
+			return nil, st.trapAt(start, "Netstring length %d exceeds remaining input", n)
+		}
+		if err := st.expectByte(',', "after netstring payload"); err != nil {
+			return nil, err
+		}
+		return payload, nil
+	}
+}
+
+// take consumes exactly n bytes and returns them, or fails without
+// consuming anything if fewer remain.
+func (st *ParseState) take(n int) (string, bool) {
+	if len(st.Source)-st.Pos < n {
+		st.sawEnd = true
+		return "", false
+	}
+	s := st.Source[st.Pos : st.Pos+n]
+	st.advance(n)
+	return s, true
+}
@@ -0,0 +1,221 @@
+package parsec
+
+import (
+	"strconv"
+)
+
+// NumberOptions configures the numeric parsers.
+type NumberOptions struct {
+	// Underscores permits single '_' separators between digits, as in
+	// 1_000_000. Leading, trailing and doubled separators are errors.
+	Underscores bool
+	// Precision is the mantissa precision in bits of BigFloat results. Zero
+	// selects 64.
+	Precision uint
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// scanDigits consumes a run of digits in the given base and returns them
+// with any separators removed. Decimal digits beyond the base are reported
+// as errors rather than ending the run.
+func (st *ParseState) scanDigits(base int, underscores bool) (string, error) {
+	begin := st.Pos
+	digits := make([]byte, 0, 16)
+	for ; !st.atEnd(st.Pos); st.Pos++ {
+		c := st.Source[st.Pos]
+		if c == '_' && underscores && len(digits) > 0 {
+			if st.Source[st.Pos-1] == '_' {
+				return "", st.trap("Unexpected '_' in number")
+			}
+			continue
+		}
+		v := hexValue(c)
+		if v < 0 || base <= 10 && v >= 10 {
+			break
+		}
+		if v >= base {
+			return "", st.trap("Invalid digit '%c' in base-%d literal", c, base)
+		}
+		digits = append(digits, c)
+	}
+	if st.Pos > begin && st.Source[st.Pos-1] == '_' {
+		st.Pos--
+		return "", st.trap("Trailing '_' in number")
+	}
+	return string(digits), nil
+}
+
+// Integer parses an optionally signed decimal integer and returns it as an
+// int64. Values out of range are reported at the start of the number.
+func Integer(st *ParseState) (interface{}, error) {
+	return integer(st, NumberOptions{})
+}
+
+func IntegerWith(opts NumberOptions) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		return integer(st, opts)
+	}
+}
+
+func integer(st *ParseState, opts NumberOptions) (interface{}, error) {
+	start, begin := st.Position(), st.Pos
+	clean, err := st.scanInteger(opts)
+	if err != nil {
+		return nil, err
+	}
+	v, err := strconv.ParseInt(clean, 10, 64)
+	if err != nil {
+		return nil, st.trapAt(start, "Integer %s out of range", st.Source[begin:st.Pos])
+	}
+	return v, nil
+}
+
+// scanInteger consumes a signed decimal integer and returns its text
+// without separators.
+func (st *ParseState) scanInteger(opts NumberOptions) (string, error) {
+	begin := st.Pos
+	st.next(func(c byte) bool { return c == '+' || c == '-' })
+	sign := st.Source[begin:st.Pos]
+	digits, err := st.scanDigits(10, opts.Underscores)
+	if err != nil {
+		return "", err
+	}
+	if digits == "" {
+		st.Pos = begin
+		return "", st.trap("Expected integer")
+	}
+	return sign + digits, nil
+}
+
+type RadixInt struct {
+	Value int64
+	Base  int
+}
+
+// RadixInteger parses an optionally signed integer with a 0x, 0o or 0b
+// prefix, or a leading zero for octal, and returns a RadixInt holding the
+// value and the base it was written in.
+func RadixInteger(st *ParseState) (interface{}, error) {
+	return radixInteger(st, NumberOptions{})
+}
+
+func RadixIntegerWith(opts NumberOptions) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		return radixInteger(st, opts)
+	}
+}
+
+func radixInteger(st *ParseState, opts NumberOptions) (interface{}, error) {
+	start, begin := st.Position(), st.Pos
+	st.next(func(c byte) bool { return c == '+' || c == '-' })
+	sign := st.Source[begin:st.Pos]
+	base := 10
+	if rest := st.peek(2); len(rest) > 1 && rest[0] == '0' {
+		switch rest[1] {
+		case 'x', 'X':
+			base = 16
+			st.advance(2)
+		case 'o', 'O':
+			base = 8
+			st.advance(2)
+		case 'b', 'B':
+			base = 2
+			st.advance(2)
+		default:
+			if isDigit(rest[1]) {
+				base = 8
+				st.advance(1)
+			}
+		}
+	}
+	digits, err := st.scanDigits(base, opts.Underscores)
+	if err != nil {
+		return nil, err
+	}
+	if digits == "" {
+		if base == 10 {
+			st.Pos = begin
+			return nil, st.trap("Expected integer")
+		}
+		return nil, st.trap("Expected base-%d digits", base)
+	}
+	v, err := strconv.ParseInt(sign+digits, base, 64)
+	if err != nil {
+		return nil, st.trapAt(start, "Integer %s out of range", st.Source[begin:st.Pos])
+	}
+	return RadixInt{Value: v, Base: base}, nil
+}
+
+type FloatLit struct {
+	Value float64
+	Text  string
+}
+
+// Float parses an optionally signed decimal number with an optional
+// fraction and exponent, such as 1, 1.5, .5, 1e10 or -1.5e-3, and returns a
+// FloatLit. A '.' or exponent marker that is not followed by digits is left
+// unconsumed, so "1.x" and "1em" parse as 1.
+func Float(st *ParseState) (interface{}, error) {
+	return float(st, NumberOptions{})
+}
+
+func FloatWith(opts NumberOptions) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		return float(st, opts)
+	}
+}
+
+func float(st *ParseState, opts NumberOptions) (interface{}, error) {
+	start, begin := st.Position(), st.Pos
+	clean, err := st.scanFloat(opts)
+	if err != nil {
+		return nil, err
+	}
+	v, err := strconv.ParseFloat(clean, 64)
+	if err != nil {
+		return nil, st.trapAt(start, "Number %s out of range", st.Source[begin:st.Pos])
+	}
+	return FloatLit{Value: v, Text: st.Source[begin:st.Pos]}, nil
+}
+
+// scanFloat consumes a number in the syntax accepted by Float and returns
+// its text without separators.
+func (st *ParseState) scanFloat(opts NumberOptions) (string, error) {
+	begin := st.Pos
+	st.next(func(c byte) bool { return c == '+' || c == '-' })
+	clean := st.Source[begin:st.Pos]
+	whole, err := st.scanDigits(10, opts.Underscores)
+	if err != nil {
+		return "", err
+	}
+	clean += whole
+	if rest := st.peek(2); len(rest) > 1 && rest[0] == '.' && isDigit(rest[1]) {
+		st.Pos++
+		frac, err := st.scanDigits(10, opts.Underscores)
+		if err != nil {
+			return "", err
+		}
+		clean += "." + frac
+	} else if whole == "" {
+		st.Pos = begin
+		return "", st.trap("Expected number")
+	}
+	if mark := st.Pos; !st.atEnd(st.Pos) && (st.Source[st.Pos] == 'e' || st.Source[st.Pos] == 'E') {
+		st.Pos++
+		st.next(func(c byte) bool { return c == '+' || c == '-' })
+		sign := st.Source[mark+1 : st.Pos]
+		exp, err := st.scanDigits(10, opts.Underscores)
+		if err != nil {
+			return "", err
+		}
+		if exp == "" {
+			st.Pos = mark
+		} else {
+			clean += "e" + sign + exp
+		}
+	}
+	return clean, nil
+}
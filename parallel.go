@@ -0,0 +1,73 @@
+package parsec
+
+// Clone returns a copy of the state that can be advanced independently of
+// st. User and Hooks are shared with st, not copied.
+func (st *ParseState) Clone() *ParseState {
+	c := *st
+	return &c
+}
+
+// adopt takes over the progress of c, a clone of st, keeping st's own
+// Arena, interning table and Grammar rule state, which c may not share.
+func (st *ParseState) adopt(c *ParseState) {
+	st.Pos, st.Line, st.bit, st.cut = c.Pos, c.Line, c.bit, c.cut
+	st.User, st.partial, st.recoveries = c.User, c.partial, c.recoveries
+}
+
+type branchResult struct {
+	st  *ParseState
+	x   interface{}
+	err error
+}
+
+// ParallelEither tries each of ps on its own clone of the state, all at
+// once in separate goroutines, and commits to the leftmost that succeeds,
+// as Try(p1).Or(Try(p2))... would but without waiting for one alternative
+// to fail before starting the next. It pays off for expensive alternatives
+// such as the complete grammars of competing formats. A branch failing
+// after Cut ends the choice as it would in Either. If all fail, the error
+// of the one that got furthest is returned and no input is consumed.
+//
+// Branches must not modify User, and parsers that run after the winner is
+// known may keep running in the background until they finish. With Hooks
+// set the branches run one after another, so that hooks see a coherent
+// sequence of events. Branches running concurrently do not use the parse's
+// Arena or interning table, nor the results of memoized Grammar rules.
+func ParallelEither(ps ...Parser) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		results := make([]chan branchResult, len(ps))
+		for i, p := range ps {
+			results[i] = make(chan branchResult, 1)
+			run := func(p Parser, c *ParseState, out chan<- branchResult) {
+				x, err := p(c)
+				out <- branchResult{c, x, err}
+			}
+			if st.Hooks != nil {
+				run(p, st.Clone(), results[i])
+			} else {
+				c := st.Clone()
+				c.arena, c.interned, c.rules = nil, nil, st.activeRules()
+				go run(p, c, results[i])
+			}
+		}
+		var furthest *branchResult
+		sawEnd := st.sawEnd
+		for _, c := range results {
+			r := <-c
+			sawEnd = sawEnd || r.st.sawEnd
+			if r.err == nil || r.st.cut {
+				st.adopt(r.st)
+				st.sawEnd = sawEnd
+				return r.x, r.err
+			}
+			if furthest == nil || r.st.Pos > furthest.st.Pos {
+				furthest = &r
+			}
+		}
+		st.sawEnd = sawEnd
+		if furthest == nil {
+			return nil, st.trap("No alternatives")
+		}
+		return nil, furthest.err
+	}
+}
@@ -1,8 +1,8 @@
 package parsec
 
 import (
-	"bytes"
 	"fmt"
+	"unicode/utf8"
 )
 
 type Parser func(*ParseState) (interface{}, error)
@@ -15,41 +15,93 @@ var Digit = OneOf([]byte("0123456789"))
 var Digits = Many1(Digit)
 var AlphaNum = Either(Letter, Digit)
 var AlphaNums = Many1(AlphaNum)
-var HexDigit = OneOf([]byte("0123456789acdefABCDEF"))
+var HexDigit = OneOf([]byte("0123456789abcdefABCDEF"))
 var HexDigits = Many1(HexDigit)
 var Punctuation = OneOf([]byte("!@#$%^&*()-=+[]{}\\|;:'\",./<>?~`"))
 var Space = OneOf([]byte(" \t"))
-var Spaces = Skip(Space)
-var Newline = OneOf([]byte("\r\n"))
+var Spaces = SkipWhile(func(c byte) bool { return c == ' ' || c == '\t' })
+var Newline Parser = func(st *ParseState) (interface{}, error) {
+	if _, ok := st.next(func(c byte) bool { return c == '\n' }); ok {
+		return byte('\n'), nil
+	}
+	if _, ok := st.next(func(c byte) bool { return c == '\r' }); ok {
+		st.next(func(c byte) bool { return c == '\n' })
+		return byte('\n'), nil
+	}
+	return nil, st.trap("Expected newline")
+}
 var Eol = Either(Eof, Newline)
 
 type ParseState struct {
 	Source string
 	Pos    int
 	Line   int
+	Hooks  Hooks
+	// User is state threaded through a parse for the grammar's own use,
+	// such as symbol tables. It is not restored when parsers backtrack.
+	User interface{}
+
+	inputErr error
+	origin   []offsetMapping
+	fold     bool
+	cut      bool
+	sawEnd   bool
+	bit      int // bits of the byte at Pos already read by Bits
+
+	littleEndian bool
+	alignOrigin  int
+	file         string
+	arena        *Arena
+	interned     map[string]string
+	keepPartial  bool
+	partial      interface{}
+	recoveries   int
+	rules        map[ruleKey]*ruleEntry
+}
+
+type ParseOption func(*ParseState)
+
+// WithUserState sets the initial value of ParseState.User.
+func WithUserState(v interface{}) ParseOption {
+	return func(st *ParseState) {
+		st.User = v
+	}
 }
 
 type ParseErr struct {
 	Reason string
 	Line   int
+	Offset int    // byte offset in the original input
+	File   string // set by ParseFile and ParseFS
 }
 
 func (err ParseErr) Error() string {
+	if err.File != "" {
+		return fmt.Sprintf("%s on line %d of %s", err.Reason, err.Line, err.File)
+	}
 	return fmt.Sprintf("%s on line %d", err.Reason, err.Line)
 }
 
-func (p Parser) Parse(source string) (interface{}, error) {
+func (p Parser) Parse(source string, opts ...ParseOption) (interface{}, error) {
 	st := ParseState{Source: source, Line: 1, Pos: 0}
-	return p(&st)
+	for _, opt := range opts {
+		opt(&st)
+	}
+	if st.inputErr != nil {
+		return nil, st.inputErr
+	}
+	return st.finish(p(&st))
 }
 
 func (st *ParseState) next(pred func(byte) bool) (byte, bool) {
-	if st.Pos < len(st.Source) {
+	if st.Pos >= len(st.Source) {
+		st.sawEnd = true
+	} else {
 		if c := st.Source[st.Pos]; pred(c) == false {
 			return c, false
 		} else {
 			st.Pos++
-			if c == '\n' {
+			if c == '\n' || c == '\r' && (st.Pos == len(st.Source) || st.Source[st.Pos] != '\n') {
 				st.Line++
 			}
 			return c, true
@@ -58,8 +110,56 @@ func (st *ParseState) next(pred func(byte) bool) (byte, bool) {
 	return '\000', false
 }
 
+// atEnd reports whether i is at or past the end of the input, noting that
+// the parse looked there, so that a Feeder reruns it with more input rather
+// than take a run or lookahead cut short by the end as complete.
+func (st *ParseState) atEnd(i int) bool {
+	if i >= len(st.Source) {
+		st.sawEnd = true
+		return true
+	}
+	return false
+}
+
+// peek returns the next n bytes of input, or the rest of it if shorter, in
+// which case the parse is taken to have reached the end as by atEnd.
+func (st *ParseState) peek(n int) string {
+	if st.atEnd(st.Pos + n - 1) {
+		return st.Source[min(st.Pos, len(st.Source)):]
+	}
+	return st.Source[st.Pos : st.Pos+n]
+}
+
+// hasPrefix reports whether the input continues with s.
+func (st *ParseState) hasPrefix(s string) bool {
+	return st.peek(len(s)) == s
+}
+
+func (st *ParseState) skipWhile(pred func(byte) bool) int {
+	end := st.Pos
+	for end < len(st.Source) && pred(st.Source[end]) {
+		end++
+	}
+	if end == len(st.Source) {
+		st.sawEnd = true
+	}
+	n := end - st.Pos
+	st.advance(n)
+	return n
+}
+
 func (st *ParseState) trap(format string, args ...interface{}) ParseErr {
-	return ParseErr{Line: st.Line, Reason: fmt.Sprintf(format, args...)}
+	return ParseErr{Line: st.Line, Offset: st.originOffset(st.Pos), File: st.file, Reason: fmt.Sprintf(format, args...)}
+}
+
+func (st *ParseState) trapAt(pos Position, format string, args ...interface{}) ParseErr {
+	return ParseErr{Line: pos.Line, Offset: pos.Offset, File: st.file, Reason: fmt.Sprintf(format, args...)}
+}
+
+// ErrorAt returns a ParseErr located at pos, for parsers that detect a
+// problem only after consuming the offending input.
+func (st *ParseState) ErrorAt(pos Position, format string, args ...interface{}) error {
+	return st.trapAt(pos, format, args...)
 }
 
 func (p Parser) Bind(f func(interface{}) Parser) Parser {
@@ -81,6 +181,27 @@ func (p1 Parser) Then(p2 Parser) Parser {
 	}
 }
 
+// As runs p and returns v instead of p's result, as when mapping the
+// literal "true" to the bool true. p's errors are returned unchanged, so
+// Label applies to As(v) as it does to p.
+func (p Parser) As(v interface{}) Parser {
+	return p.Then(Return(v))
+}
+
+// ThenSkip runs p and then q, and returns p's result.
+func (p Parser) ThenSkip(q Parser) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		x, err := p(st)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := q(st); err != nil {
+			return nil, err
+		}
+		return x, nil
+	}
+}
+
 func Return(x interface{}) Parser {
 	return func(st *ParseState) (interface{}, error) {
 		return x, nil
@@ -89,18 +210,18 @@ func Return(x interface{}) Parser {
 
 func Fail(msg string) Parser {
 	return func(st *ParseState) (interface{}, error) {
-		return nil, st.trap(msg)
+		return nil, st.trap("%s", msg)
 	}
 }
 
 func Either(p1, p2 Parser) Parser {
 	return func(st *ParseState) (interface{}, error) {
-		oldPos := st.Pos
+		oldPos, oldBit := st.Pos, st.bit
 		x, err := p1(st)
 		if err == nil {
 			return x, nil
 		}
-		if st.Pos == oldPos {
+		if st.Pos == oldPos && st.bit == oldBit && !st.cut {
 			return p2(st)
 		}
 		return nil, err
@@ -111,13 +232,20 @@ func (p Parser) Or(p2 Parser) Parser {
 	return Either(p, p2)
 }
 
+// OrValue returns v if p fails without consuming input.
+func (p Parser) OrValue(v interface{}) Parser {
+	return p.Or(Return(v))
+}
+
 func Try(p Parser) Parser {
 	return func(st *ParseState) (interface{}, error) {
-		oldPos := st.Pos
+		oldPos, oldLine, oldBit := st.Pos, st.Line, st.bit
 		if x, err := p(st); err == nil {
 			return x, nil
 		} else {
-			st.Pos = oldPos
+			if !st.cut {
+				st.Pos, st.Line, st.bit = oldPos, oldLine, oldBit
+			}
 			return nil, err
 		}
 	}
@@ -131,15 +259,16 @@ func AnyChar(st *ParseState) (interface{}, error) {
 }
 
 func Eof(st *ParseState) (interface{}, error) {
-	if c, ok := st.next(func(x byte) bool { return true }); ok {
-		return nil, st.trap("Expected end of file but got '%c'", c)
+	if st.Pos < len(st.Source) {
+		return nil, st.trap("Expected end of file but got '%c'", st.Source[st.Pos])
 	}
+	st.sawEnd = true
 	return nil, nil
 }
 
 func Char(c byte) Parser {
 	return func(st *ParseState) (interface{}, error) {
-		if x, ok := st.next(func(b byte) bool { return b == c }); ok {
+		if x, ok := st.next(func(b byte) bool { return st.sameByte(b, c) }); ok {
 			return x, nil
 		} else {
 			return nil, st.trap("Expected '%c'", c)
@@ -149,7 +278,7 @@ func Char(c byte) Parser {
 
 func OneOf(set []byte) Parser {
 	return func(st *ParseState) (interface{}, error) {
-		if x, ok := st.next(func(c byte) bool { return bytes.IndexByte(set, c) >= 0 }); ok {
+		if x, ok := st.next(func(c byte) bool { return st.byteIn(set, c) }); ok {
 			return x, nil
 		} else {
 			return nil, st.trap("Expected one of '%s' but got '%c'", string(set), x)
@@ -159,7 +288,7 @@ func OneOf(set []byte) Parser {
 
 func NoneOf(set []byte) Parser {
 	return func(st *ParseState) (interface{}, error) {
-		if x, ok := st.next(func(c byte) bool { return bytes.IndexByte(set, c) < 0 }); ok {
+		if x, ok := st.next(func(c byte) bool { return !st.byteIn(set, c) }); ok {
 			return x, nil
 		} else {
 			return nil, st.trap("Unexpected '%c'", x)
@@ -169,13 +298,16 @@ func NoneOf(set []byte) Parser {
 
 func String(s string) Parser {
 	return func(st *ParseState) (interface{}, error) {
-		oldPos := st.Pos
+		if st.fold {
+			return st.stringFold(s)
+		}
+		oldPos, oldLine := st.Pos, st.Line
 
 		for _, c := range []byte(s) {
 			_, ok := st.next(func(b byte) bool { return b == c })
 
 			if ok == false {
-				st.Pos = oldPos
+				st.Pos, st.Line = oldPos, oldLine
 				return nil, st.trap("Expected '%s'", s)
 			}
 		}
@@ -184,38 +316,96 @@ func String(s string) Parser {
 }
 
 func (p Parser) ToString() Parser {
-	return p.Bind(func(x interface{}) Parser {
-		var bs []byte = make([]byte, len(x.([]interface{})))
-		for i, c := range x.([]interface{}) {
-			bs[i] = c.(byte)
+	return func(st *ParseState) (interface{}, error) {
+		x, err := p(st)
+		if err != nil {
+			return nil, err
 		}
-		return Return(string(bs))
-	})
-}
-
-func appendx(x, xs interface{}) interface{} {
-	return append([]interface{}{x}, xs.([]interface{})...)
+		var bs []byte = make([]byte, 0, len(x.([]interface{})))
+		for _, c := range x.([]interface{}) {
+			switch c := c.(type) {
+			case byte:
+				bs = append(bs, c)
+			case rune:
+				bs = utf8.AppendRune(bs, c)
+			case string:
+				bs = append(bs, c...)
+			}
+		}
+		return st.internBytes(bs), nil
+	}
 }
 
 func Many1(p Parser) Parser {
-	return p.Bind(func(x interface{}) Parser {
-		return Many(p).Bind(func(xs interface{}) Parser {
-			return Return(appendx(x, xs))
-		})
-	})
+	return func(st *ParseState) (interface{}, error) {
+		x, err := p(st)
+		if err != nil {
+			return nil, err
+		}
+		return st.many(p, x)
+	}
 }
 
 func Many(p Parser) Parser {
-	return Many1(p).Or(Return([]interface{}{}))
+	return func(st *ParseState) (interface{}, error) {
+		return st.many(p)
+	}
 }
 
 func ManyTill(p, end Parser) Parser {
-	return Either(Try(end).Then(Return([]interface{}{})),
-		p.Bind(func(x interface{}) Parser {
-			return ManyTill(p, end).Bind(func(xs interface{}) Parser {
-				return Return(appendx(x, xs))
-			})
-		}))
+	end = Try(end)
+	return func(st *ParseState) (interface{}, error) {
+		buf := getScratch()
+		defer putScratch(buf)
+		for {
+			oldPos, oldBit := st.Pos, st.bit
+			if _, err := end(st); err == nil {
+				return st.results(*buf), nil
+			} else if st.Pos != oldPos || st.bit != oldBit || st.cut {
+				return nil, err
+			}
+			st.partial = nil
+			x, err := p(st)
+			if err != nil {
+				return nil, st.failPartial(*buf, err)
+			}
+			*buf = append(*buf, x)
+		}
+	}
+}
+
+// Flatten runs p and returns its result with nested []interface{} lists,
+// as built by combining Many and SepBy, spliced into one flat list. A
+// result that is not a list becomes a list of one.
+func Flatten(p Parser) Parser {
+	return FlattenDepth(p, -1)
+}
+
+// FlattenDepth is Flatten splicing only the first depth levels of nested
+// lists. A negative depth splices all of them.
+func FlattenDepth(p Parser, depth int) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		x, err := p(st)
+		if err != nil {
+			return nil, err
+		}
+		xs, ok := x.([]interface{})
+		if !ok {
+			return []interface{}{x}, nil
+		}
+		return flatten(make([]interface{}, 0, len(xs)), xs, depth), nil
+	}
+}
+
+func flatten(dst, xs []interface{}, depth int) []interface{} {
+	for _, x := range xs {
+		if inner, ok := x.([]interface{}); ok && depth != 0 {
+			dst = flatten(dst, inner, depth-1)
+		} else {
+			dst = append(dst, x)
+		}
+	}
+	return dst
 }
 
 func Skip(p Parser) Parser {
@@ -223,22 +413,90 @@ func Skip(p Parser) Parser {
 }
 
 func SkipMany(p Parser) Parser {
-	return Skip(Many(p))
+	return func(st *ParseState) (interface{}, error) {
+		for {
+			oldPos, oldBit := st.Pos, st.bit
+			if _, err := p(st); err != nil {
+				if st.Pos != oldPos || st.bit != oldBit || st.cut {
+					return nil, err
+				}
+				return nil, nil
+			}
+		}
+	}
+}
+
+// SkipWhile skips the bytes satisfying pred, possibly none, in a single
+// pass over the input. It is a faster SkipMany for single-byte parsers such
+// as Space.
+func SkipWhile(pred func(byte) bool) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		st.skipWhile(pred)
+		return nil, nil
+	}
 }
 
 func (p Parser) Between(start, end Parser) Parser {
-	keep := func(x interface{}) Parser { return end.Then(Return(x)) }
-	return start.Then(p.Bind(keep))
+	return start.Then(p.ThenSkip(end))
+}
+
+// Padded skips Spaces before and after p and returns p's result.
+func Padded(p Parser) Parser {
+	return PaddedBy(Spaces, p)
+}
+
+// PaddedBy is Padded skipping space, such as a parser for whitespace and
+// comments, instead of Spaces.
+func PaddedBy(space, p Parser) Parser {
+	return p.Between(space, space)
 }
 
 func (p Parser) SepBy1(sep Parser) Parser {
-	return p.Bind(func(x interface{}) Parser {
-		return Many(sep.Then(p)).Bind(func(xs interface{}) Parser {
-			return Return(appendx(x, xs))
-		})
-	})
+	rest := sep.Then(p)
+	return func(st *ParseState) (interface{}, error) {
+		x, err := p(st)
+		if err != nil {
+			return nil, err
+		}
+		return st.many(rest, x)
+	}
 }
 
 func (p Parser) SepBy(sep Parser) Parser {
 	return p.SepBy1(sep).Or(Return([]interface{}{}))
 }
+
+func (p Parser) SepEndBy1(sep Parser) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		x, err := p(st)
+		if err != nil {
+			return nil, err
+		}
+		buf := getScratch()
+		defer putScratch(buf)
+		*buf = append(*buf, x)
+		for {
+			oldPos, oldBit := st.Pos, st.bit
+			st.partial = nil
+			if _, err := sep(st); err != nil {
+				if st.Pos != oldPos || st.bit != oldBit || st.cut {
+					return nil, st.failPartial(*buf, err)
+				}
+				return st.results(*buf), nil
+			}
+			oldPos, oldBit = st.Pos, st.bit
+			x, err := p(st)
+			if err != nil {
+				if st.Pos != oldPos || st.bit != oldBit || st.cut {
+					return nil, st.failPartial(*buf, err)
+				}
+				return st.results(*buf), nil
+			}
+			*buf = append(*buf, x)
+		}
+	}
+}
+
+func (p Parser) SepEndBy(sep Parser) Parser {
+	return p.SepEndBy1(sep).Or(Return([]interface{}{}))
+}
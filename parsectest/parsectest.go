@@ -0,0 +1,185 @@
+// Package parsectest helps test grammars against golden files. Render
+// writes a parse result and error in a stable text form that diffs well,
+// and Golden compares it with a file kept next to the tests, rewriting the
+// file instead when the tests run with -update-golden.
+package parsectest
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"parsec"
+)
+
+var update = flag.Bool("update-golden", false, "rewrite golden files with the results of the tests")
+
+// Render formats x and err one value per line, nesting by indentation.
+// Map keys are sorted, so the form of a result does not change from run to
+// run. Positions are written line:offset, and errors that join several,
+// such as toml.Errors, are listed one by one.
+func Render(x interface{}, err error) string {
+	var b strings.Builder
+	b.WriteString("result: ")
+	render(&b, reflect.ValueOf(x), 0)
+	b.WriteString("\nerror: ")
+	renderErr(&b, err, 0)
+	b.WriteString("\n")
+	return b.String()
+}
+
+func pos(p parsec.Position) string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Offset)
+}
+
+func span(s parsec.Span) string {
+	return pos(s.Start) + "-" + pos(s.End)
+}
+
+func newline(b *strings.Builder, depth int) {
+	b.WriteString("\n")
+	b.WriteString(strings.Repeat("  ", depth))
+}
+
+func renderErr(b *strings.Builder, err error, depth int) {
+	switch e := err.(type) {
+	case nil:
+		b.WriteString("nil")
+	case parsec.ParseErr:
+		fmt.Fprintf(b, "%q at %d:%d", e.Reason, e.Line, e.Offset)
+		if e.File != "" {
+			fmt.Fprintf(b, " in %s", e.File)
+		}
+	case interface{ Unwrap() []error }:
+		errs := e.Unwrap()
+		fmt.Fprintf(b, "%d errors", len(errs))
+		for _, err := range errs {
+			newline(b, depth+1)
+			renderErr(b, err, depth+1)
+		}
+	default:
+		fmt.Fprintf(b, "%q", err.Error())
+	}
+}
+
+func render(b *strings.Builder, v reflect.Value, depth int) {
+	if !v.IsValid() {
+		b.WriteString("nil")
+		return
+	}
+	switch x := v.Interface().(type) {
+	case parsec.Span:
+		b.WriteString(span(x))
+		return
+	case parsec.Position:
+		b.WriteString(pos(x))
+		return
+	case parsec.Spanned:
+		b.WriteString(span(x.Span) + " ")
+		render(b, reflect.ValueOf(x.Value), depth)
+		return
+	case parsec.ErrorNode:
+		b.WriteString(span(x.Span) + " error node ")
+		renderErr(b, x.Err, depth)
+		return
+	case error:
+		if v.Kind() != reflect.Struct {
+			renderErr(b, x, depth)
+			return
+		}
+	}
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			b.WriteString("nil")
+			return
+		}
+		render(b, v.Elem(), depth)
+	case reflect.String:
+		b.WriteString(strconv.Quote(v.String()))
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			fmt.Fprintf(b, "bytes %q", v.Bytes())
+			return
+		}
+		if v.Len() == 0 {
+			b.WriteString("[]")
+			return
+		}
+		b.WriteString("[")
+		for i := 0; i < v.Len(); i++ {
+			newline(b, depth+1)
+			render(b, v.Index(i), depth+1)
+		}
+		newline(b, depth)
+		b.WriteString("]")
+	case reflect.Map:
+		keys := make([]string, 0, v.Len())
+		values := make(map[string]reflect.Value, v.Len())
+		for it := v.MapRange(); it.Next(); {
+			var kb strings.Builder
+			render(&kb, it.Key(), depth+1)
+			keys = append(keys, kb.String())
+			values[kb.String()] = it.Value()
+		}
+		sort.Strings(keys)
+		if len(keys) == 0 {
+			b.WriteString("{}")
+			return
+		}
+		b.WriteString("{")
+		for _, k := range keys {
+			newline(b, depth+1)
+			b.WriteString(k + ": ")
+			render(b, values[k], depth+1)
+		}
+		newline(b, depth)
+		b.WriteString("}")
+	case reflect.Struct:
+		t := v.Type()
+		b.WriteString(t.Name() + "{")
+		for i := 0; i < t.NumField(); i++ {
+			if !t.Field(i).IsExported() {
+				continue
+			}
+			newline(b, depth+1)
+			b.WriteString(t.Field(i).Name + ": ")
+			render(b, v.Field(i), depth+1)
+		}
+		newline(b, depth)
+		b.WriteString("}")
+	default:
+		fmt.Fprintf(b, "%s(%#v)", v.Type(), v.Interface())
+	}
+}
+
+// Golden compares Render(x, err) with the golden file at path, such as
+// "testdata/config.golden", failing t with both texts if they differ. With
+// the -update-golden flag it writes the file instead, creating its
+// directory if needed.
+func Golden(t testing.TB, path string, x interface{}, err error) {
+	t.Helper()
+	got := Render(x, err)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, rerr := os.ReadFile(path)
+	if rerr != nil {
+		t.Fatalf("%v (run with -update-golden to create it)", rerr)
+	}
+	if got != string(want) {
+		t.Errorf("result differs from %s (run with -update-golden to accept it)\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
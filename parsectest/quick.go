@@ -0,0 +1,73 @@
+package parsectest
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+
+	"parsec"
+)
+
+// check runs prop on inputs from generate with testing/quick, failing t
+// with the first input prop rejects and the reason it gives.
+func check(t testing.TB, generate func(*rand.Rand) string, config *quick.Config, prop func(input string) string) {
+	t.Helper()
+	var c quick.Config
+	if config != nil {
+		c = *config
+	}
+	c.Values = func(args []reflect.Value, r *rand.Rand) {
+		args[0] = reflect.ValueOf(generate(r))
+	}
+	var failure string
+	err := quick.Check(func(input string) bool {
+		failure = prop(input)
+		return failure == ""
+	}, &c)
+	if err != nil {
+		if failure == "" {
+			t.Fatal(err)
+		}
+		t.Errorf("%s", failure)
+	}
+}
+
+// CheckParses checks that p parses all of every input generate produces,
+// for as many random cases as config asks for, or testing/quick's default
+// if config is nil.
+func CheckParses(t testing.TB, p parsec.Parser, generate func(*rand.Rand) string, config *quick.Config) {
+	t.Helper()
+	whole := p.Then(parsec.Eof)
+	check(t, generate, config, func(input string) string {
+		if _, err := whole.Parse(input); err != nil {
+			return fmt.Sprintf("parsing %q: %v", input, err)
+		}
+		return ""
+	})
+}
+
+// CheckRoundTrip checks that printing the result of parsing an input gives
+// the input back, as normalize would write it, for random inputs from
+// generate as in CheckParses. A nil normalize expects the input unchanged.
+func CheckRoundTrip(t testing.TB, p parsec.Parser, print func(interface{}) string, normalize func(string) string, generate func(*rand.Rand) string, config *quick.Config) {
+	t.Helper()
+	whole := p.Bind(func(x interface{}) parsec.Parser {
+		return parsec.Parser(parsec.Eof).Then(parsec.Return(x))
+	})
+	check(t, generate, config, func(input string) string {
+		x, err := whole.Parse(input)
+		if err != nil {
+			return fmt.Sprintf("parsing %q: %v", input, err)
+		}
+		want := input
+		if normalize != nil {
+			want = normalize(input)
+		}
+		if got := print(x); got != want {
+			return fmt.Sprintf("parsing %q and printing it gave %q, want %q", input, got, want)
+		}
+		return ""
+	})
+}
@@ -0,0 +1,35 @@
+package parsec
+
+// WithPartialResults makes a failed parse return, along with its error,
+// what it had parsed before failing instead of nil. The partial result is
+// that of the innermost repetition to fail, such as Many or SepBy: a
+// []interface{} of the results it had collected, followed by the partial
+// result of the element that failed, if that has one in turn. A document
+// parsed as Many(record) thus yields the records before the bad one and as
+// much of the bad one as its own repetitions got through.
+func WithPartialResults() ParseOption {
+	return func(st *ParseState) {
+		st.keepPartial = true
+	}
+}
+
+// failPartial records xs and the partial result of the element that just
+// failed as the partial result of the parse, and returns err.
+func (st *ParseState) failPartial(xs []interface{}, err error) error {
+	if !st.keepPartial {
+		return err
+	}
+	partial := append(make([]interface{}, 0, len(xs)+1), xs...)
+	if st.partial != nil {
+		partial = append(partial, st.partial)
+	}
+	st.partial = partial
+	return err
+}
+
+func (st *ParseState) finish(x interface{}, err error) (interface{}, error) {
+	if err != nil && st.keepPartial {
+		return st.partial, err
+	}
+	return x, err
+}
@@ -0,0 +1,126 @@
+package parsec
+
+import "sync"
+
+// Repetition collects results in a scratch buffer taken from scratchPool
+// and copies them into a slice of the exact size when it ends, so that
+// a long Many allocates once instead of growing and copying as it goes.
+var scratchPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]interface{}, 0, 16)
+		return &buf
+	},
+}
+
+// maxScratch is the capacity above which a scratch buffer is left to the
+// garbage collector rather than kept for reuse.
+const maxScratch = 1 << 16
+
+func getScratch() *[]interface{} {
+	return scratchPool.Get().(*[]interface{})
+}
+
+func putScratch(buf *[]interface{}) {
+	if cap(*buf) > maxScratch {
+		return
+	}
+	clear(*buf)
+	*buf = (*buf)[:0]
+	scratchPool.Put(buf)
+}
+
+// many runs p until it fails without consuming input and returns its
+// results after those in head.
+func (st *ParseState) many(p Parser, head ...interface{}) (interface{}, error) {
+	buf := getScratch()
+	defer putScratch(buf)
+	*buf = append(*buf, head...)
+	for {
+		oldPos, oldBit := st.Pos, st.bit
+		st.partial = nil
+		x, err := p(st)
+		if err != nil {
+			if st.Pos != oldPos || st.bit != oldBit || st.cut {
+				return nil, st.failPartial(*buf, err)
+			}
+			return st.results(*buf), nil
+		}
+		*buf = append(*buf, x)
+	}
+}
+
+// results copies xs out of a scratch buffer, into the parse's arena if it
+// has one.
+func (st *ParseState) results(xs []interface{}) []interface{} {
+	if st.arena != nil {
+		return st.arena.alloc(xs)
+	}
+	if len(xs) == 0 {
+		return []interface{}{}
+	}
+	return append(make([]interface{}, 0, len(xs)), xs...)
+}
+
+const (
+	arenaBlock = 4096
+	// Slices longer than arenaLarge get their own allocation, so that they
+	// do not waste most of a block.
+	arenaLarge = arenaBlock / 4
+)
+
+// An Arena holds the slices returned by Many, SepBy and the other
+// repetition combinators in large blocks that are reused from one parse to
+// the next, for programs parsing many small documents whose results are
+// discarded soon after. The slices stay valid until Reset, which the
+// caller must not call while it still uses results of a parse with the
+// arena. An Arena must not be used by two parses at once.
+type Arena struct {
+	blocks [][]interface{}
+	block  int
+	free   []interface{}
+}
+
+func NewArena() *Arena {
+	return &Arena{}
+}
+
+// WithArena allocates the results of repetition from a.
+func WithArena(a *Arena) ParseOption {
+	return func(st *ParseState) {
+		st.arena = a
+	}
+}
+
+func (a *Arena) alloc(xs []interface{}) []interface{} {
+	n := len(xs)
+	switch {
+	case n == 0:
+		return []interface{}{}
+	case n > arenaLarge:
+		return append(make([]interface{}, 0, n), xs...)
+	case len(a.free) < n:
+		if a.blocks != nil {
+			a.block++
+		}
+		if a.block == len(a.blocks) {
+			a.blocks = append(a.blocks, make([]interface{}, arenaBlock))
+		}
+		a.free = a.blocks[a.block]
+	}
+	s := a.free[:n:n]
+	copy(s, xs)
+	a.free = a.free[n:]
+	return s
+}
+
+// Reset releases every slice allocated from a for reuse by later parses.
+func (a *Arena) Reset() {
+	if a.blocks == nil {
+		return
+	}
+	for _, b := range a.blocks[:a.block+1] {
+		clear(b)
+	}
+	a.block = 0
+	a.free = a.blocks[0]
+}
@@ -0,0 +1,174 @@
+// Package pretty lays out documents built from text, line breaks, nesting
+// and groups to fit a page width, in the style of Wadler's prettier
+// printer. It is the printing counterpart of parsec grammars: a printer
+// turns a parse result into a Doc, and Render lays it out.
+//
+// A group is printed on one line if it fits in the remaining width, with
+// each Line in it printed as a space and each SoftLine as nothing.
+// Otherwise its lines break, each starting a new line indented by the
+// Nest levels enclosing it. Groups inside a broken group are laid out in
+// turn.
+package pretty
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Doc is a document to lay out.
+type Doc interface {
+	doc()
+}
+
+type text string
+type line struct {
+	flat string
+	hard bool
+}
+type concat []Doc
+type nest struct {
+	indent int
+	d      Doc
+}
+type group struct {
+	d Doc
+}
+
+func (text) doc()   {}
+func (line) doc()   {}
+func (concat) doc() {}
+func (nest) doc()   {}
+func (group) doc()  {}
+
+var (
+	// Line is a line break, or a space in a group laid out flat.
+	Line Doc = line{flat: " "}
+	// SoftLine is a line break, or nothing in a group laid out flat.
+	SoftLine Doc = line{}
+	// HardLine is always a line break, and breaks the groups around it.
+	HardLine Doc = line{hard: true}
+)
+
+// Text is s, which should not contain newlines.
+func Text(s string) Doc {
+	return text(s)
+}
+
+func Concat(docs ...Doc) Doc {
+	return concat(docs)
+}
+
+// Nest indents the lines d breaks onto by n more columns.
+func Nest(n int, d Doc) Doc {
+	return nest{n, d}
+}
+
+// Group lays out d on one line if it fits.
+func Group(d Doc) Doc {
+	return group{d}
+}
+
+// Join places sep between docs.
+func Join(sep Doc, docs []Doc) Doc {
+	joined := make(concat, 0, 2*len(docs))
+	for i, d := range docs {
+		if i > 0 {
+			joined = append(joined, sep)
+		}
+		joined = append(joined, d)
+	}
+	return joined
+}
+
+// Bracket lays out d between open and close, on one line if it fits and
+// otherwise with d on lines of its own indented by n, as for the elements
+// of a list.
+func Bracket(open string, n int, d Doc, close string) Doc {
+	return Group(Concat(Text(open), Nest(n, Concat(SoftLine, d)), SoftLine, Text(close)))
+}
+
+type item struct {
+	indent int
+	flat   bool
+	d      Doc
+}
+
+// fits reports whether the next line of output, starting with first and
+// continuing with rest, fits in w columns.
+func fits(w int, first item, rest []item) bool {
+	stack := []item{first}
+	for w >= 0 {
+		if len(stack) == 0 {
+			if len(rest) == 0 {
+				return true
+			}
+			stack = append(stack, rest[len(rest)-1])
+			rest = rest[:len(rest)-1]
+		}
+		it := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		switch d := it.d.(type) {
+		case text:
+			w -= utf8.RuneCountInString(string(d))
+		case line:
+			if !it.flat {
+				return true
+			}
+			if d.hard {
+				return false
+			}
+			w -= len(d.flat)
+		case concat:
+			for i := len(d) - 1; i >= 0; i-- {
+				stack = append(stack, item{it.indent, it.flat, d[i]})
+			}
+		case nest:
+			stack = append(stack, item{it.indent + d.indent, it.flat, d.d})
+		case group:
+			stack = append(stack, item{it.indent, it.flat, d.d})
+		}
+	}
+	return false
+}
+
+// Fprint lays out d to fit in width columns and writes it to w.
+func Fprint(w io.Writer, width int, d Doc) error {
+	_, err := io.WriteString(w, Render(width, d))
+	return err
+}
+
+// Render lays out d to fit in width columns where it can.
+func Render(width int, d Doc) string {
+	var b strings.Builder
+	col := 0
+	stack := []item{{0, false, d}}
+	for len(stack) > 0 {
+		it := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		switch d := it.d.(type) {
+		case text:
+			b.WriteString(string(d))
+			col += utf8.RuneCountInString(string(d))
+		case line:
+			if it.flat && !d.hard {
+				b.WriteString(d.flat)
+				col += len(d.flat)
+				break
+			}
+			b.WriteByte('\n')
+			b.WriteString(strings.Repeat(" ", it.indent))
+			col = it.indent
+		case concat:
+			for i := len(d) - 1; i >= 0; i-- {
+				stack = append(stack, item{it.indent, it.flat, d[i]})
+			}
+		case nest:
+			stack = append(stack, item{it.indent + d.indent, it.flat, d.d})
+		case group:
+			flat := it.flat || fits(width-col, item{it.indent, true, d.d}, stack)
+			stack = append(stack, item{it.indent, flat, d.d})
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,103 @@
+package parsec
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+type RuleStats struct {
+	Name        string
+	Invocations int
+	Successes   int
+	Failures    int
+	Backtracks  int // invocations at an offset the rule was already tried at
+	Consumed    int // bytes consumed by successful invocations
+	Time        time.Duration
+}
+
+// Profiler is a Hooks implementation collecting RuleStats for every labeled
+// parser. Time is cumulative: it includes nested rules, but recursive
+// invocations of a rule are only counted once.
+type Profiler struct {
+	stats map[string]*RuleStats
+	tried map[string]map[int]bool
+	depth map[string]int
+	stack []profileFrame
+}
+
+type profileFrame struct {
+	start int
+	began time.Time
+}
+
+func NewProfiler() *Profiler {
+	return &Profiler{
+		stats: make(map[string]*RuleStats),
+		tried: make(map[string]map[int]bool),
+		depth: make(map[string]int),
+	}
+}
+
+func (pr *Profiler) rule(name string) *RuleStats {
+	rs, ok := pr.stats[name]
+	if !ok {
+		rs = &RuleStats{Name: name}
+		pr.stats[name] = rs
+		pr.tried[name] = make(map[int]bool)
+	}
+	return rs
+}
+
+func (pr *Profiler) OnEnter(name string, pos Position) {
+	rs := pr.rule(name)
+	rs.Invocations++
+	if pr.tried[name][pos.Offset] {
+		rs.Backtracks++
+	}
+	pr.tried[name][pos.Offset] = true
+	pr.depth[name]++
+	pr.stack = append(pr.stack, profileFrame{start: pos.Offset, began: time.Now()})
+}
+
+func (pr *Profiler) OnExit(name string, pos Position, result interface{}, err error) {
+	frame := pr.stack[len(pr.stack)-1]
+	pr.stack = pr.stack[:len(pr.stack)-1]
+	rs := pr.rule(name)
+	if err != nil {
+		rs.Failures++
+	} else {
+		rs.Successes++
+		rs.Consumed += pos.Offset - frame.start
+	}
+	if pr.depth[name]--; pr.depth[name] == 0 {
+		rs.Time += time.Since(frame.began)
+	}
+}
+
+// Report returns the collected statistics, most expensive rule first.
+func (pr *Profiler) Report() []RuleStats {
+	report := make([]RuleStats, 0, len(pr.stats))
+	for _, rs := range pr.stats {
+		report = append(report, *rs)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].Time != report[j].Time {
+			return report[i].Time > report[j].Time
+		}
+		return report[i].Name < report[j].Name
+	})
+	return report
+}
+
+func (pr *Profiler) WriteReport(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "rule\tcalls\tok\tfail\tbacktracks\tbytes\ttime\t")
+	for _, rs := range pr.Report() {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%s\t\n",
+			rs.Name, rs.Invocations, rs.Successes, rs.Failures, rs.Backtracks, rs.Consumed, rs.Time)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,128 @@
+// Package properties parses Java .properties files.
+package properties
+
+import (
+	"strconv"
+	"unicode/utf16"
+
+	"parsec"
+)
+
+type Property struct {
+	Key   string
+	Value string
+	Span  parsec.Span // the key
+}
+
+var blank = parsec.OneOf([]byte(" \t\f"))
+var blanks = parsec.SkipMany(blank)
+
+// continuation joins a line ending in a backslash to the next, dropping
+// the next line's leading whitespace.
+var continuation = parsec.Try(parsec.Char('\\').Then(parsec.Newline)).Then(blanks).Then(parsec.Return(""))
+
+// gap skips whitespace between a key, its separator and its value.
+var gap = parsec.SkipMany(blank.Or(continuation))
+
+var escapes = map[byte]byte{'t': '\t', 'n': '\n', 'r': '\r', 'f': '\f'}
+
+// escape decodes a backslash escape. \uXXXX escapes are UTF-16, so a
+// surrogate pair written as two escapes decodes to a single rune; other
+// escaped characters stand for themselves.
+func escape(st *parsec.ParseState) (interface{}, error) {
+	start := st.Position()
+	if _, err := parsec.Char('\\')(st); err != nil {
+		return nil, err
+	}
+	if st.Pos == len(st.Source) {
+		return "", nil
+	}
+	if st.Source[st.Pos] != 'u' {
+		c, _ := parsec.AnyChar(st)
+		if e, ok := escapes[c.(byte)]; ok {
+			return e, nil
+		}
+		return c, nil
+	}
+	r, err := utf16Unit(st, start)
+	if err != nil {
+		return nil, err
+	}
+	if utf16.IsSurrogate(r) {
+		rest := st.Source[st.Pos:]
+		if len(rest) >= 2 && rest[:2] == `\u` {
+			st.Pos++
+			low, err := utf16Unit(st, start)
+			if err != nil {
+				return nil, err
+			}
+			r = utf16.DecodeRune(r, low)
+		}
+	}
+	return r, nil
+}
+
+// utf16Unit decodes the u and four hex digits of a \uXXXX escape.
+func utf16Unit(st *parsec.ParseState, start parsec.Position) (rune, error) {
+	if len(st.Source)-st.Pos < 5 {
+		return 0, st.ErrorAt(start, "Invalid \\uXXXX escape sequence")
+	}
+	v, err := strconv.ParseUint(st.Source[st.Pos+1:st.Pos+5], 16, 16)
+	if err != nil {
+		return 0, st.ErrorAt(start, "Invalid \\uXXXX escape sequence")
+	}
+	st.Pos += 5
+	return rune(v), nil
+}
+
+func char(stop string) parsec.Parser {
+	return continuation.Or(escape).Or(parsec.NoneOf([]byte(stop + "\\\r\n")))
+}
+
+var key = parsec.Many(char(" \t\f=:")).ToString().WithSpan()
+var value = parsec.Many(char("")).ToString()
+
+var entry = key.Bind(func(k interface{}) parsec.Parser {
+	return gap.Then(parsec.Skip(parsec.OneOf([]byte("=:")))).Then(gap).Then(value).Bind(func(v interface{}) parsec.Parser {
+		k := k.(parsec.Spanned)
+		return parsec.Return(Property{Key: k.Value.(string), Value: v.(string), Span: k.Span})
+	})
+})
+
+var comment = parsec.OneOf([]byte("#!")).Then(parsec.SkipMany(parsec.NoneOf([]byte("\r\n"))))
+
+func notEOF(st *parsec.ParseState) (interface{}, error) {
+	if st.Pos < len(st.Source) {
+		return nil, nil
+	}
+	return parsec.Fail("Unexpected end of file")(st)
+}
+
+// line parses a blank line, a comment or an entry, returning nil for the
+// first two.
+var line = parsec.Parser(notEOF).Then(blanks).Then(
+	comment.Or(parsec.Return(nil)).Then(parsec.Eol).Then(parsec.Return(nil)).
+		Or(entry.Bind(func(x interface{}) parsec.Parser {
+			return parsec.Eol.Then(parsec.Return(x))
+		})))
+
+// Grammar parses a complete file into a []Property in file order.
+var Grammar = parsec.Many(line).Bind(func(x interface{}) parsec.Parser {
+	var props []Property
+	for _, p := range x.([]interface{}) {
+		if p != nil {
+			props = append(props, p.(Property))
+		}
+	}
+	return parsec.Parser(parsec.Eof).Then(parsec.Return(props))
+})
+
+// Parse parses a .properties file. Keys may repeat; later entries
+// conventionally override earlier ones.
+func Parse(source string) ([]Property, error) {
+	x, err := Grammar.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	return x.([]Property), nil
+}
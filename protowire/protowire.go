@@ -0,0 +1,252 @@
+// Package protowire provides parsers for the protocol buffers wire format,
+// for decoding simple messages by hand without generated code.
+// FieldParser reads a field of any type; Embedded parses a
+// length-delimited payload in place with a further parser, such as
+// Message for a nested message.
+package protowire
+
+import (
+	"parsec"
+)
+
+type WireType int
+
+const (
+	VarintType     WireType = 0
+	Fixed64Type    WireType = 1
+	BytesType      WireType = 2
+	StartGroupType WireType = 3
+	EndGroupType   WireType = 4
+	Fixed32Type    WireType = 5
+)
+
+const MaxFieldNumber = 1<<29 - 1
+
+type Tag struct {
+	Number int
+	Type   WireType
+}
+
+// Field is a decoded field. Value is a uint64 for varint and fixed-width
+// fields, a string sharing the input's memory for length-delimited ones,
+// and a []Field for groups.
+type Field struct {
+	Tag   Tag
+	Value interface{}
+	Span  parsec.Span
+}
+
+// varint reads a base-128 varint, reporting errors at start.
+func varint(st *parsec.ParseState, start parsec.Position) (uint64, error) {
+	var v uint64
+	for i := 0; ; i++ {
+		if st.Pos >= len(st.Source) {
+			if i == 0 {
+				return 0, st.ErrorAt(start, "Expected varint")
+			}
+			return 0, st.ErrorAt(start, "Truncated varint")
+		}
+		c := st.Source[st.Pos]
+		st.Pos++
+		if i == 9 && c > 1 {
+			return 0, st.ErrorAt(start, "Varint overflows 64 bits")
+		}
+		v |= uint64(c&0x7f) << (7 * i)
+		if c&0x80 == 0 {
+			return v, nil
+		}
+	}
+}
+
+// Varint parses a varint and returns it as a uint64.
+func Varint(st *parsec.ParseState) (interface{}, error) {
+	if st.Pos >= len(st.Source) {
+		return parsec.Fail("Expected varint")(st)
+	}
+	v, err := varint(st, st.Position())
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// DecodeZigZag maps the zigzag encoding used by sint32 and sint64 fields
+// back to a signed value.
+func DecodeZigZag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+// SignedVarint parses a zigzag-encoded varint and returns it as an int64.
+var SignedVarint = parsec.Parser(Varint).Bind(func(v interface{}) parsec.Parser {
+	return parsec.Return(DecodeZigZag(v.(uint64)))
+})
+
+// fixed reads an n-byte little-endian value.
+func fixed(st *parsec.ParseState, n int, what string) (uint64, error) {
+	if len(st.Source)-st.Pos < n {
+		return 0, st.ErrorAt(st.Position(), "Truncated %s", what)
+	}
+	var v uint64
+	for i := n - 1; i >= 0; i-- {
+		v = v<<8 | uint64(st.Source[st.Pos+i])
+	}
+	st.Pos += n
+	return v, nil
+}
+
+// Fixed32 parses a little-endian 32-bit value and returns it as a uint32.
+func Fixed32(st *parsec.ParseState) (interface{}, error) {
+	v, err := fixed(st, 4, "fixed32")
+	if err != nil {
+		return nil, err
+	}
+	return uint32(v), nil
+}
+
+// Fixed64 parses a little-endian 64-bit value and returns it as a uint64.
+func Fixed64(st *parsec.ParseState) (interface{}, error) {
+	v, err := fixed(st, 8, "fixed64")
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// length reads the length of a length-delimited field and checks it
+// against the remaining input.
+func length(st *parsec.ParseState) (int, error) {
+	start := st.Position()
+	n, err := varint(st, start)
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64(len(st.Source)-st.Pos) {
+		return 0, st.ErrorAt(start, "Length %d exceeds remaining input", n)
+	}
+	return int(n), nil
+}
+
+// LengthDelimited parses a length-prefixed payload and returns it as a
+// string sharing the input's memory.
+func LengthDelimited(st *parsec.ParseState) (interface{}, error) {
+	n, err := length(st)
+	if err != nil {
+		return nil, err
+	}
+	s := st.Source[st.Pos : st.Pos+n]
+	st.Pos += n
+	return s, nil
+}
+
+// Embedded returns a parser for a length-prefixed payload parsed by p,
+// which must consume all of it. p sees the payload in place, so its
+// positions are relative to the whole input.
+func Embedded(p parsec.Parser) parsec.Parser {
+	return func(st *parsec.ParseState) (interface{}, error) {
+		n, err := length(st)
+		if err != nil {
+			return nil, err
+		}
+		source, end := st.Source, st.Pos+n
+		st.Source = source[:end]
+		x, err := p(st)
+		if err == nil && st.Pos < end {
+			err = st.ErrorAt(st.Position(), "Unexpected data at end of embedded message")
+		}
+		st.Source = source
+		if err != nil {
+			return nil, err
+		}
+		return x, nil
+	}
+}
+
+// FieldTag parses a field key and returns it as a Tag.
+func FieldTag(st *parsec.ParseState) (interface{}, error) {
+	if st.Pos >= len(st.Source) {
+		return parsec.Fail("Expected field tag")(st)
+	}
+	start := st.Position()
+	v, err := varint(st, start)
+	if err != nil {
+		return nil, err
+	}
+	t := Tag{Number: int(v >> 3), Type: WireType(v & 7)}
+	switch {
+	case t.Type > Fixed32Type:
+		return nil, st.ErrorAt(start, "Invalid wire type %d", t.Type)
+	case v>>3 == 0 || v>>3 > MaxFieldNumber:
+		return nil, st.ErrorAt(start, "Invalid field number %d", v>>3)
+	}
+	return t, nil
+}
+
+// FieldParser parses a field of any wire type and returns it as a Field.
+// Groups are read up to their matching end tag.
+func FieldParser(st *parsec.ParseState) (interface{}, error) {
+	start := st.Position()
+	x, err := FieldTag(st)
+	if err != nil {
+		return nil, err
+	}
+	f := Field{Tag: x.(Tag)}
+	switch f.Tag.Type {
+	case VarintType:
+		f.Value, err = varint(st, st.Position())
+	case Fixed64Type:
+		f.Value, err = fixed(st, 8, "fixed64")
+	case Fixed32Type:
+		f.Value, err = fixed(st, 4, "fixed32")
+	case BytesType:
+		f.Value, err = LengthDelimited(st)
+	case StartGroupType:
+		f.Value, err = group(st, f.Tag.Number, start)
+	case EndGroupType:
+		return nil, st.ErrorAt(start, "Unmatched end of group %d", f.Tag.Number)
+	}
+	if err != nil {
+		return nil, err
+	}
+	f.Span = parsec.Span{Start: start, End: st.Position()}
+	return f, nil
+}
+
+func group(st *parsec.ParseState, number int, start parsec.Position) ([]Field, error) {
+	fields := []Field{}
+	for {
+		if st.Pos >= len(st.Source) {
+			return nil, st.ErrorAt(start, "Unterminated group %d", number)
+		}
+		pos, p := st.Position(), st.Pos
+		x, err := FieldTag(st)
+		if err != nil {
+			return nil, err
+		}
+		if t := x.(Tag); t.Type == EndGroupType {
+			if t.Number != number {
+				return nil, st.ErrorAt(pos, "End of group %d inside group %d", t.Number, number)
+			}
+			return fields, nil
+		}
+		st.Pos = p
+		f, err := FieldParser(st)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f.(Field))
+	}
+}
+
+// Message parses fields up to the end of input and returns them as a
+// []Field, in the order they appear.
+func Message(st *parsec.ParseState) (interface{}, error) {
+	fields := []Field{}
+	for st.Pos < len(st.Source) {
+		f, err := FieldParser(st)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f.(Field))
+	}
+	return fields, nil
+}
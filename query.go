@@ -0,0 +1,86 @@
+package parsec
+
+import (
+	"bytes"
+	"strings"
+)
+
+type QueryParam struct {
+	Key, Value string
+}
+
+// Query is a decoded query string, in the order the parameters appeared.
+type Query []QueryParam
+
+// Get returns the first value for key.
+func (q Query) Get(key string) (string, bool) {
+	for _, p := range q {
+		if p.Key == key {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// percentDecode consumes text up to a byte satisfying stop and returns it
+// with %XX escapes decoded, and '+' decoded as a space if plus is set.
+func (st *ParseState) percentDecode(stop func(byte) bool, plus bool) (string, error) {
+	var sb strings.Builder
+	for !st.atEnd(st.Pos) && !stop(st.Source[st.Pos]) {
+		switch c := st.Source[st.Pos]; {
+		case c == '%':
+			st.Pos++
+			hi, lo := st.hexPair()
+			if hi < 0 || lo < 0 {
+				st.Pos--
+				return "", st.trap("Invalid percent-encoding")
+			}
+			sb.WriteByte(byte(hi<<4 | lo))
+			st.Pos += 2
+		case c == '+' && plus:
+			sb.WriteByte(' ')
+			st.Pos++
+		default:
+			sb.WriteByte(c)
+			st.advance(1)
+		}
+	}
+	return sb.String(), nil
+}
+
+// PercentDecoded consumes text up to the end of input or the first byte in
+// stop and returns it with %XX escapes decoded. A '%' not followed by two
+// hex digits is an error.
+func PercentDecoded(stop []byte) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		return st.percentDecode(func(c byte) bool { return bytes.IndexByte(stop, c) >= 0 }, false)
+	}
+}
+
+// QueryString parses an application/x-www-form-urlencoded query string,
+// with '&' or ';' separating parameters, up to a '#', whitespace or the end
+// of input, and returns a Query. Repeated keys are kept in order.
+func QueryString(st *ParseState) (interface{}, error) {
+	endOfQuery := func(c byte) bool { return c == '#' || c <= ' ' || c == '\x7f' }
+	endOfParam := func(c byte) bool { return c == '&' || c == ';' || endOfQuery(c) }
+	endOfKey := func(c byte) bool { return c == '=' || endOfParam(c) }
+	q := Query{}
+	for !st.atEnd(st.Pos) && !endOfQuery(st.Source[st.Pos]) {
+		if endOfParam(st.Source[st.Pos]) {
+			st.Pos++
+			continue
+		}
+		key, err := st.percentDecode(endOfKey, true)
+		if err != nil {
+			return nil, err
+		}
+		value := ""
+		if _, ok := st.next(func(c byte) bool { return c == '=' }); ok {
+			if value, err = st.percentDecode(endOfParam, true); err != nil {
+				return nil, err
+			}
+		}
+		q = append(q, QueryParam{Key: key, Value: value})
+	}
+	return q, nil
+}
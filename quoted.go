@@ -0,0 +1,239 @@
+package parsec
+
+import (
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// QuoteOptions configures QuotedString.
+type QuoteOptions struct {
+	// Quotes lists the accepted quote characters; a string must be closed
+	// with the character that opened it. Defaults to `"`.
+	Quotes string
+	// Escape is the escape character, or 0 if escapes are not supported.
+	Escape rune
+	// Escapes maps the character following Escape to its replacement. The
+	// quote characters and Escape itself always escape to themselves; any
+	// other sequence is an error.
+	Escapes map[rune]string
+	// HexEscapes enables the \xNN, \uXXXX and \UXXXXXXXX escapes, using
+	// Escape in place of the backslash.
+	HexEscapes bool
+	// UTF16Escapes enables only the \uXXXX escape, as in JSON.
+	UTF16Escapes bool
+	// Multiline permits unescaped newlines inside the string.
+	Multiline bool
+	// NoControl rejects other unescaped control characters (U+0000 to
+	// U+001F), as JSON does.
+	NoControl bool
+}
+
+var CEscapes = map[rune]string{
+	'a': "\a", 'b': "\b", 'f': "\f", 'n': "\n", 'r': "\r", 't': "\t", 'v': "\v", '0': "\x00",
+}
+
+var DoubleQuoted = QuotedString(QuoteOptions{Quotes: `"`, Escape: '\\', Escapes: CEscapes, HexEscapes: true})
+var SingleQuoted = QuotedString(QuoteOptions{Quotes: `'`, Escape: '\\', Escapes: CEscapes, HexEscapes: true})
+
+var BacktickString = RawString("`", "`")
+var TripleQuoted = Either(RawString(`"""`, `"""`), RawString("'''", "'''"))
+
+var cEscape = QuoteOptions{Quotes: `"'`, Escape: '\\', Escapes: CEscapes, HexEscapes: true}
+
+// EscapedChar parses a C-style escape sequence such as \n, \x41 or \u00e9
+// and returns the rune it denotes.
+func EscapedChar(st *ParseState) (interface{}, error) {
+	start := st.Position()
+	if _, ok := st.next(func(c byte) bool { return c == '\\' }); !ok {
+		return nil, st.trap("Expected escape sequence")
+	}
+	s, err := cEscape.unescape(st, start)
+	if err != nil {
+		return nil, err
+	}
+	r, _ := utf8.DecodeRuneInString(s)
+	return r, nil
+}
+
+// QuotedString parses a quoted string and returns its unescaped value.
+func QuotedString(opts QuoteOptions) Parser {
+	if opts.Quotes == "" {
+		opts.Quotes = `"`
+	}
+	return func(st *ParseState) (interface{}, error) {
+		q, size, err := st.peekRune()
+		if err != nil || !strings.ContainsRune(opts.Quotes, q) {
+			return nil, st.trap("Expected quoted string")
+		}
+		st.advance(size)
+		var sb strings.Builder
+		for {
+			r, size, err := st.peekRune()
+			if err != nil {
+				if st.Pos >= len(st.Source) {
+					return nil, st.trap("Unterminated string")
+				}
+				return nil, err
+			}
+			switch {
+			case r == q:
+				st.advance(size)
+				return sb.String(), nil
+			case opts.Escape != 0 && r == opts.Escape:
+				start := st.Position()
+				st.advance(size)
+				s, err := opts.unescape(st, start)
+				if err != nil {
+					return nil, err
+				}
+				sb.WriteString(s)
+			case (r == '\n' || r == '\r') && !opts.Multiline:
+				return nil, st.trap("Unexpected newline in string")
+			case r < ' ' && opts.NoControl:
+				return nil, st.trap("Unexpected control character U+%04X in string", r)
+			default:
+				st.advance(size)
+				sb.WriteRune(r)
+			}
+		}
+	}
+}
+
+// CharLiteral parses a single-quoted character literal such as 'a', '\n'
+// or '\u00e9' and returns its rune value as a Spanned.
+func CharLiteral(st *ParseState) (interface{}, error) {
+	start := st.Position()
+	if _, ok := st.next(func(c byte) bool { return c == '\'' }); !ok {
+		return nil, st.trap("Expected character literal")
+	}
+	r, size, err := st.peekRune()
+	switch {
+	case err != nil:
+		return nil, err
+	case r == '\'':
+		return nil, st.trapAt(start, "Empty character literal")
+	case r == '\n' || r == '\r':
+		return nil, st.trap("Unexpected newline in character literal")
+	case r == '\\':
+		escape := st.Position()
+		st.advance(size)
+		s, err := cEscape.unescape(st, escape)
+		if err != nil {
+			return nil, err
+		}
+		r, _ = utf8.DecodeRuneInString(s)
+	default:
+		st.advance(size)
+	}
+	if _, ok := st.next(func(c byte) bool { return c == '\'' }); !ok {
+		return nil, st.trapAt(start, "Character literal must contain exactly one character")
+	}
+	return Spanned{Value: r, Span: Span{Start: start, End: st.Position()}}, nil
+}
+
+// unescape decodes the escape sequence following an escape character found
+// at start. Errors are reported at start.
+func (opts *QuoteOptions) unescape(st *ParseState, start Position) (string, error) {
+	r, size, err := st.peekRune()
+	if err != nil {
+		return "", st.trapAt(start, "Unterminated escape sequence")
+	}
+	if s, ok := opts.Escapes[r]; ok {
+		st.advance(size)
+		return s, nil
+	}
+	if r == opts.Escape || strings.ContainsRune(opts.Quotes, r) {
+		st.advance(size)
+		return string(r), nil
+	}
+	if opts.UTF16Escapes && r == 'u' {
+		st.advance(size)
+		return opts.unicodeEscape(st, start)
+	}
+	if opts.HexEscapes {
+		switch r {
+		case 'x':
+			st.advance(size)
+			v, err := st.hexEscape(start, 2)
+			return string(v), err
+		case 'u':
+			st.advance(size)
+			return opts.unicodeEscape(st, start)
+		case 'U':
+			st.advance(size)
+			v, err := st.hexEscape(start, 8)
+			if err == nil && !utf8.ValidRune(v) {
+				err = st.trapAt(start, "Invalid Unicode code point U+%X", v)
+			}
+			return string(v), err
+		}
+	}
+	return "", st.trapAt(start, "Unknown escape sequence '%c%c'", opts.Escape, r)
+}
+
+// unicodeEscape decodes the digits of a \uXXXX escape, combining a UTF-16
+// surrogate pair written as two consecutive escapes.
+func (opts *QuoteOptions) unicodeEscape(st *ParseState, start Position) (string, error) {
+	v, err := st.hexEscape(start, 4)
+	if err != nil {
+		return "", err
+	}
+	if utf16.IsSurrogate(v) {
+		if v < 0xDC00 && strings.HasPrefix(st.Source[st.Pos:], string(opts.Escape)+"u") {
+			st.advance(utf8.RuneLen(opts.Escape) + 1)
+			low, err := st.hexEscape(start, 4)
+			if err != nil {
+				return "", err
+			}
+			if v = utf16.DecodeRune(v, low); v != utf8.RuneError {
+				return string(v), nil
+			}
+		}
+		return "", st.trapAt(start, "Invalid UTF-16 surrogate in escape sequence")
+	}
+	return string(v), nil
+}
+
+func (st *ParseState) hexEscape(start Position, digits int) (rune, error) {
+	var v rune
+	for i := 0; i < digits; i++ {
+		c, ok := st.next(func(c byte) bool { return hexValue(c) >= 0 })
+		if !ok {
+			return 0, st.trapAt(start, "Invalid escape sequence: expected %d hex digits", digits)
+		}
+		v = v<<4 | rune(hexValue(c))
+	}
+	return v, nil
+}
+
+func hexValue(c byte) int {
+	switch {
+	case '0' <= c && c <= '9':
+		return int(c - '0')
+	case 'a' <= c && c <= 'f':
+		return int(c-'a') + 10
+	case 'A' <= c && c <= 'F':
+		return int(c-'A') + 10
+	}
+	return -1
+}
+
+// RawString parses text between the open and close delimiters verbatim,
+// including newlines, and returns it without the delimiters.
+func RawString(open, close string) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		start := st.Position()
+		if _, err := String(open)(st); err != nil {
+			return nil, err
+		}
+		n := strings.Index(st.Source[st.Pos:], close)
+		if n < 0 {
+			st.sawEnd = true
+			return nil, st.trapAt(start, "Unterminated raw string")
+		}
+		s := st.Source[st.Pos : st.Pos+n]
+		st.advance(n + len(close))
+		return s, nil
+	}
+}
@@ -0,0 +1,99 @@
+package parsec
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+type TraceNode struct {
+	Rule     string       `json:"rule"`
+	Span     Span         `json:"span"`
+	Ok       bool         `json:"ok"`
+	Error    string       `json:"error,omitempty"`
+	Children []*TraceNode `json:"children,omitempty"`
+}
+
+// Recorder is a Hooks implementation recording every labeled parser
+// invocation of a run as a tree, including the ones that failed and were
+// backtracked over.
+type Recorder struct {
+	roots []*TraceNode
+	stack []*TraceNode
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (r *Recorder) OnEnter(name string, pos Position) {
+	node := &TraceNode{Rule: name, Span: Span{Start: pos, End: pos}}
+	if n := len(r.stack); n > 0 {
+		r.stack[n-1].Children = append(r.stack[n-1].Children, node)
+	} else {
+		r.roots = append(r.roots, node)
+	}
+	r.stack = append(r.stack, node)
+}
+
+func (r *Recorder) OnExit(name string, pos Position, result interface{}, err error) {
+	node := r.stack[len(r.stack)-1]
+	r.stack = r.stack[:len(r.stack)-1]
+	node.Span.End = pos
+	node.Ok = err == nil
+	if err != nil {
+		node.Error = err.Error()
+	}
+}
+
+func (r *Recorder) Roots() []*TraceNode {
+	return r.roots
+}
+
+func (r *Recorder) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r.roots)
+}
+
+// WriteDot writes the recorded run as a Graphviz digraph. Successful
+// invocations are drawn green and failed ones red.
+func (r *Recorder) WriteDot(w io.Writer) error {
+	if _, err := fmt.Fprintln(w, "digraph parse {\n\tnode [shape=box, style=filled];"); err != nil {
+		return err
+	}
+	id := 0
+	var walk func(node *TraceNode, parent int) error
+	walk = func(node *TraceNode, parent int) error {
+		id++
+		self := id
+		color := "palegreen"
+		label := fmt.Sprintf("%s\n[%d, %d)", node.Rule, node.Span.Start.Offset, node.Span.End.Offset)
+		if !node.Ok {
+			color = "lightpink"
+			label += "\n" + node.Error
+		}
+		if _, err := fmt.Fprintf(w, "\tn%d [label=%s, fillcolor=%s];\n", self, strconv.Quote(label), color); err != nil {
+			return err
+		}
+		if parent > 0 {
+			if _, err := fmt.Fprintf(w, "\tn%d -> n%d;\n", parent, self); err != nil {
+				return err
+			}
+		}
+		for _, child := range node.Children {
+			if err := walk(child, self); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, root := range r.roots {
+		if err := walk(root, 0); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
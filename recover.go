@@ -0,0 +1,38 @@
+package parsec
+
+// ErrorNode stands in a result for input that could not be parsed and was
+// skipped by Recover, so that formatters and linters working on the result
+// can tell which regions it does not describe.
+type ErrorNode struct {
+	Span Span
+	Err  error
+}
+
+// Recover returns a parser that behaves like p, except that when p fails
+// it skips input with sync, such as SkipUntil(";") or a parser for the
+// rest of a line, and returns an ErrorNode for the input from where p
+// started to where sync stopped instead of the error. A Cut in p applies
+// only up to the recovery. Errors other than a ParseErr, and failures
+// that would leave the parse where p started, are returned as they are, so
+// that Many(Recover(p, sync)) ends at input nothing can skip, such as the
+// end of input. Recoveries are counted in Metrics.Recoveries.
+func Recover(p, sync Parser) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		start, pos := st.Position(), st.Pos
+		x, err := p(st)
+		if err == nil {
+			return x, nil
+		}
+		if _, ok := err.(ParseErr); !ok {
+			return nil, err
+		}
+		cut := st.cut
+		st.cut = false
+		if _, serr := sync(st); serr != nil || st.Pos == pos {
+			st.cut = cut
+			return nil, err
+		}
+		st.recoveries++
+		return ErrorNode{Span: Span{Start: start, End: st.Position()}, Err: err}, nil
+	}
+}
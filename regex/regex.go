@@ -0,0 +1,402 @@
+// Package regex parses regular expressions into a syntax tree with
+// positions, so that patterns can be analyzed and errors reported where
+// they occur. It accepts a common subset of Perl and RE2 syntax:
+// alternation, capturing, non-capturing and named groups, character
+// classes with ranges and the \d, \w and \s escapes, greedy and lazy
+// quantifiers, and the ^, $, \b and \B anchors.
+package regex
+
+import (
+	"strconv"
+	"unicode"
+
+	"parsec"
+)
+
+type Kind int
+
+const (
+	Literal Kind = iota
+	AnyChar
+	Class
+	Begin
+	End
+	WordBoundary
+	NotWordBoundary
+	Group
+	Concat
+	Alternate
+	Repeat
+)
+
+type Range struct {
+	Lo, Hi rune
+}
+
+type Node struct {
+	Kind Kind
+	Rune rune // for literals
+	// Ranges and Negated describe a character class.
+	Ranges  []Range
+	Negated bool
+	// Capture and Name describe a group.
+	Capture bool
+	Name    string
+	// Min, Max and Lazy describe a repetition. Max is -1 if unbounded.
+	Min, Max int
+	Lazy     bool
+	// Subs holds the contents of a group, repetition, concatenation or
+	// alternation.
+	Subs []*Node
+	Span parsec.Span
+}
+
+var (
+	digitRanges = []Range{{'0', '9'}}
+	wordRanges  = []Range{{'0', '9'}, {'A', 'Z'}, {'_', '_'}, {'a', 'z'}}
+	spaceRanges = []Range{{'\t', '\n'}, {'\f', '\r'}, {' ', ' '}}
+)
+
+var classEscapes = map[rune][]Range{'d': digitRanges, 'w': wordRanges, 's': spaceRanges}
+
+var charEscapes = map[rune]rune{'n': '\n', 't': '\t', 'r': '\r', 'f': '\f', 'v': '\v', 'a': '\a', '0': 0}
+
+// negate returns the complement of sorted, non-overlapping ranges.
+func negate(ranges []Range) []Range {
+	var out []Range
+	next := rune(0)
+	for _, r := range ranges {
+		if r.Lo > next {
+			out = append(out, Range{next, r.Lo - 1})
+		}
+		next = r.Hi + 1
+	}
+	if next <= unicode.MaxRune {
+		out = append(out, Range{next, unicode.MaxRune})
+	}
+	return out
+}
+
+// escape parses a backslash escape, returning a literal, class or anchor
+// node. Inside a character class anchors are not allowed.
+func escape(inClass bool) parsec.Parser {
+	return func(st *parsec.ParseState) (interface{}, error) {
+		start := st.Position()
+		if _, err := parsec.Char('\\')(st); err != nil {
+			return nil, err
+		}
+		x, err := parsec.AnyRune(st)
+		if err != nil {
+			return nil, st.ErrorAt(start, "Trailing backslash")
+		}
+		r := x.(rune)
+		if ranges, ok := classEscapes[unicode.ToLower(r)]; ok {
+			return &Node{Kind: Class, Ranges: ranges, Negated: unicode.IsUpper(r)}, nil
+		}
+		if c, ok := charEscapes[r]; ok {
+			return &Node{Kind: Literal, Rune: c}, nil
+		}
+		switch {
+		case r == 'x':
+			return hexEscape(st, start)
+		case !inClass && r == 'b':
+			return &Node{Kind: WordBoundary}, nil
+		case !inClass && r == 'B':
+			return &Node{Kind: NotWordBoundary}, nil
+		case r < unicode.MaxASCII && (unicode.IsPunct(r) || unicode.IsSymbol(r)):
+			return &Node{Kind: Literal, Rune: r}, nil
+		}
+		return nil, st.ErrorAt(start, "Invalid escape sequence \\%c", r)
+	}
+}
+
+// hexEscape parses the digits of \xHH or \x{H...}.
+func hexEscape(st *parsec.ParseState, start parsec.Position) (interface{}, error) {
+	s, n := st.Source[st.Pos:], 2
+	if len(s) > 0 && s[0] == '{' {
+		for n = 1; n < len(s) && s[n] != '}'; n++ {
+		}
+		if n == len(s) {
+			return nil, st.ErrorAt(start, "Unterminated \\x{ escape")
+		}
+		s, n = s[1:n], n+1
+	} else if len(s) >= 2 {
+		s = s[:2]
+	} else {
+		return nil, st.ErrorAt(start, "Invalid hex escape")
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil || v > unicode.MaxRune {
+		return nil, st.ErrorAt(start, "Invalid hex escape")
+	}
+	st.Pos += n
+	return &Node{Kind: Literal, Rune: rune(v)}, nil
+}
+
+// classAtom parses a character or escape inside a class, returning its
+// ranges.
+var classAtom = escape(true).Or(parsec.RuneNoneOf("]")).Bind(func(x interface{}) parsec.Parser {
+	if n, ok := x.(*Node); ok {
+		if n.Kind == Literal {
+			return parsec.Return([]Range{{n.Rune, n.Rune}})
+		}
+		if n.Negated {
+			return parsec.Return(negate(n.Ranges))
+		}
+		return parsec.Return(n.Ranges)
+	}
+	return parsec.Return([]Range{{x.(rune), x.(rune)}})
+})
+
+// classItem parses a single character, an escape or a range lo-hi.
+func classItem(st *parsec.ParseState) (interface{}, error) {
+	start := st.Position()
+	x, err := classAtom(st)
+	if err != nil {
+		return nil, err
+	}
+	lo := x.([]Range)
+	rest := st.Source[st.Pos:]
+	if len(lo) != 1 || lo[0].Lo != lo[0].Hi || len(rest) < 2 || rest[0] != '-' || rest[1] == ']' {
+		return lo, nil
+	}
+	st.Pos++
+	y, err := classAtom(st)
+	if err != nil {
+		return nil, err
+	}
+	hi := y.([]Range)
+	if len(hi) != 1 || hi[0].Lo != hi[0].Hi {
+		return nil, st.ErrorAt(start, "Invalid character class range")
+	}
+	if lo[0].Lo > hi[0].Lo {
+		return nil, st.ErrorAt(start, "Invalid character class range %c-%c", lo[0].Lo, hi[0].Lo)
+	}
+	return []Range{{lo[0].Lo, hi[0].Lo}}, nil
+}
+
+// class parses a bracket expression. A ] first in the class is literal.
+func class(st *parsec.ParseState) (interface{}, error) {
+	start := st.Position()
+	if _, err := parsec.Char('[')(st); err != nil {
+		return nil, err
+	}
+	n := &Node{Kind: Class}
+	if st.Pos < len(st.Source) && st.Source[st.Pos] == '^' {
+		n.Negated = true
+		st.Pos++
+	}
+	if st.Pos < len(st.Source) && st.Source[st.Pos] == ']' {
+		n.Ranges = append(n.Ranges, Range{']', ']'})
+		st.Pos++
+	}
+	x, err := parsec.Many(classItem)(st)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := parsec.Char(']')(st); err != nil {
+		return nil, st.ErrorAt(start, "Unterminated character class")
+	}
+	for _, r := range x.([]interface{}) {
+		n.Ranges = append(n.Ranges, r.([]Range)...)
+	}
+	if len(n.Ranges) == 0 {
+		return nil, st.ErrorAt(start, "Empty character class")
+	}
+	return n, nil
+}
+
+var alternation parsec.Parser
+
+func alternationRef(st *parsec.ParseState) (interface{}, error) {
+	return alternation(st)
+}
+
+func isNameChar(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '_'
+}
+
+// group parses (re), (?:re), (?P<name>re) or (?<name>re).
+func group(st *parsec.ParseState) (interface{}, error) {
+	start := st.Position()
+	if _, err := parsec.Char('(')(st); err != nil {
+		return nil, err
+	}
+	n := &Node{Kind: Group, Capture: true}
+	s := st.Source[st.Pos:]
+	switch {
+	case len(s) >= 2 && s[:2] == "?:":
+		n.Capture = false
+		st.Pos += 2
+	case len(s) >= 3 && s[:3] == "?P<", len(s) >= 2 && s[:2] == "?<":
+		if s[1] == 'P' {
+			st.Pos++
+		}
+		st.Pos += 2
+		begin := st.Pos
+		for st.Pos < len(st.Source) && isNameChar(st.Source[st.Pos]) {
+			st.Pos++
+		}
+		n.Name = st.Source[begin:st.Pos]
+		if n.Name == "" || st.Pos == len(st.Source) || st.Source[st.Pos] != '>' {
+			return nil, st.ErrorAt(start, "Invalid group name")
+		}
+		st.Pos++
+	case len(s) >= 1 && s[0] == '?':
+		return nil, st.ErrorAt(start, "Unsupported group syntax")
+	}
+	x, err := alternationRef(st)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := parsec.Char(')')(st); err != nil {
+		return nil, st.ErrorAt(start, "Missing ')'")
+	}
+	n.Subs = []*Node{x.(*Node)}
+	return n, nil
+}
+
+func kind(k Kind) func(interface{}) parsec.Parser {
+	return func(interface{}) parsec.Parser {
+		return parsec.Return(&Node{Kind: k})
+	}
+}
+
+var nothingToRepeat = parsec.OneOf([]byte("*+?")).WithSpan().Bind(func(x interface{}) parsec.Parser {
+	return func(st *parsec.ParseState) (interface{}, error) {
+		return nil, st.ErrorAt(x.(parsec.Spanned).Span.Start, "Nothing to repeat")
+	}
+})
+
+var atom = parsec.Parser(group).
+	Or(class).
+	Or(parsec.Char('.').Bind(kind(AnyChar))).
+	Or(parsec.Char('^').Bind(kind(Begin))).
+	Or(parsec.Char('$').Bind(kind(End))).
+	Or(escape(false)).
+	Or(nothingToRepeat).
+	Or(parsec.RuneNoneOf("|)").Bind(func(x interface{}) parsec.Parser {
+		return parsec.Return(&Node{Kind: Literal, Rune: x.(rune)})
+	})).
+	WithSpan().Bind(withSpan)
+
+func withSpan(x interface{}) parsec.Parser {
+	s := x.(parsec.Spanned)
+	n := s.Value.(*Node)
+	n.Span = s.Span
+	return parsec.Return(n)
+}
+
+// count parses the bounds of {n}, {n,} or {n,m}, failing without
+// consuming input if the brace does not start a valid count, in which case
+// it is a literal.
+func count(st *parsec.ParseState) (interface{}, error) {
+	start, s := st.Position(), st.Source[st.Pos:]
+	digits := func(i int) (int, int) {
+		j := i
+		for j < len(s) && '0' <= s[j] && s[j] <= '9' {
+			j++
+		}
+		v, err := strconv.Atoi(s[i:j])
+		if err != nil {
+			return -1, j
+		}
+		return v, j
+	}
+	if len(s) == 0 || s[0] != '{' {
+		return parsec.Fail("Expected quantifier")(st)
+	}
+	min, i := digits(1)
+	max := min
+	if min >= 0 && i < len(s) && s[i] == ',' {
+		if max, i = digits(i + 1); i == len(s) || s[i] != '}' || s[i-1] == ',' {
+			max = -1
+		}
+	}
+	if min < 0 || i == len(s) || s[i] != '}' {
+		return parsec.Fail("Expected quantifier")(st)
+	}
+	st.Pos += i + 1
+	if max >= 0 && max < min || min > 1000 || max > 1000 {
+		return nil, st.ErrorAt(start, "Invalid repeat count %s", s[:i+1])
+	}
+	return [2]int{min, max}, nil
+}
+
+var quantifier = parsec.Char('*').Then(parsec.Return([2]int{0, -1})).
+	Or(parsec.Char('+').Then(parsec.Return([2]int{1, -1}))).
+	Or(parsec.Char('?').Then(parsec.Return([2]int{0, 1}))).
+	Or(count)
+
+var lazy = parsec.Char('?').Then(parsec.Return(true)).Or(parsec.Return(false))
+
+var repetition = atom.Bind(func(x interface{}) parsec.Parser {
+	n := x.(*Node)
+	return quantifier.Bind(func(q interface{}) parsec.Parser {
+		return lazy.Bind(func(l interface{}) parsec.Parser {
+			return func(st *parsec.ParseState) (interface{}, error) {
+				if n.Kind == Begin || n.Kind == End || n.Kind == WordBoundary || n.Kind == NotWordBoundary {
+					return nil, st.ErrorAt(n.Span.End, "Nothing to repeat")
+				}
+				qs := q.([2]int)
+				r := &Node{Kind: Repeat, Min: qs[0], Max: qs[1], Lazy: l.(bool), Subs: []*Node{n}}
+				r.Span = parsec.Span{Start: n.Span.Start, End: st.Position()}
+				if pos := st.Position(); quantifierFollows(st) {
+					return nil, st.ErrorAt(pos, "Nested quantifier")
+				}
+				return r, nil
+			}
+		})
+	}).Or(parsec.Return(n))
+})
+
+func quantifierFollows(st *parsec.ParseState) bool {
+	pos := st.Pos
+	_, err := quantifier(st)
+	st.Pos = pos
+	return err == nil
+}
+
+var concat = parsec.Many(repetition).WithSpan().Bind(func(x interface{}) parsec.Parser {
+	s := x.(parsec.Spanned)
+	subs := s.Value.([]interface{})
+	if len(subs) == 1 {
+		return parsec.Return(subs[0])
+	}
+	n := &Node{Kind: Concat, Subs: []*Node{}, Span: s.Span}
+	for _, sub := range subs {
+		n.Subs = append(n.Subs, sub.(*Node))
+	}
+	return parsec.Return(n)
+})
+
+func init() {
+	alternation = concat.SepBy1(parsec.Char('|')).WithSpan().Bind(func(x interface{}) parsec.Parser {
+		s := x.(parsec.Spanned)
+		alts := s.Value.([]interface{})
+		if len(alts) == 1 {
+			return parsec.Return(alts[0])
+		}
+		n := &Node{Kind: Alternate, Span: s.Span}
+		for _, alt := range alts {
+			n.Subs = append(n.Subs, alt.(*Node))
+		}
+		return parsec.Return(n)
+	})
+}
+
+// Parse parses a regular expression.
+func Parse(source string) (*Node, error) {
+	x, err := parsec.Parser(alternationRef).Bind(func(x interface{}) parsec.Parser {
+		return func(st *parsec.ParseState) (interface{}, error) {
+			if st.Pos < len(st.Source) {
+				return nil, st.ErrorAt(st.Position(), "Unmatched ')'")
+			}
+			return x, nil
+		}
+	}).Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	return x.(*Node), nil
+}
@@ -0,0 +1,24 @@
+package parsec
+
+import "regexp"
+
+// Regexp returns a parser matching the regular expression pattern at the
+// current position. It returns a []string holding the matched text
+// followed by the text of each submatch, as regexp's FindStringSubmatch
+// does. The pattern sees the remaining input only, so ^ and \b treat the
+// current position as the start of text. Under a Feeder, a match running
+// to the end of the buffered input waits for more, but a failure to match
+// is final, as a regular expression cannot tell whether more input would
+// have matched. Regexp panics if the pattern does not compile.
+func Regexp(pattern string) Parser {
+	re := regexp.MustCompile(`\A(?:` + pattern + `)`)
+	return func(st *ParseState) (interface{}, error) {
+		m := re.FindStringSubmatch(st.Source[st.Pos:])
+		if m == nil {
+			return nil, st.trap("Expected text matching /%s/", pattern)
+		}
+		st.atEnd(st.Pos + len(m[0]))
+		st.advance(len(m[0]))
+		return m, nil
+	}
+}
@@ -0,0 +1,84 @@
+package parsec
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+var UnicodeLetter = RuneIn(unicode.Letter)
+var UnicodeLetters = Many1(UnicodeLetter)
+var UnicodeUpper = RuneIn(unicode.Upper)
+var UnicodeLower = RuneIn(unicode.Lower)
+var UnicodeDigit = RuneIn(unicode.Digit)
+var UnicodeDigits = Many1(UnicodeDigit)
+var UnicodePunct = RuneIn(unicode.Punct)
+var UnicodeSpace = RuneIn(unicode.White_Space)
+var UnicodeSpaces = SkipMany(UnicodeSpace)
+
+func (st *ParseState) peekRune() (rune, int, error) {
+	if st.Pos >= len(st.Source) {
+		st.sawEnd = true
+		return 0, 0, st.trap("Unexpected end of file")
+	}
+	r, size := utf8.DecodeRuneInString(st.Source[st.Pos:])
+	if r == utf8.RuneError && size == 1 {
+		return r, size, st.trap("Invalid UTF-8 encoding")
+	}
+	return r, size, nil
+}
+
+// advance consumes n bytes, counting lines the same way next does.
+func (st *ParseState) advance(n int) {
+	for end := st.Pos + n; st.Pos < end; st.Pos++ {
+		if c := st.Source[st.Pos]; c == '\n' || c == '\r' && (st.Pos+1 == len(st.Source) || st.Source[st.Pos+1] != '\n') {
+			st.Line++
+		}
+	}
+}
+
+func satisfyRune(pred func(st *ParseState, r rune) bool, unexpected func(st *ParseState, r rune) ParseErr) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		r, size, err := st.peekRune()
+		if err != nil {
+			return nil, err
+		}
+		if !pred(st, r) {
+			return nil, unexpected(st, r)
+		}
+		st.advance(size)
+		return r, nil
+	}
+}
+
+func AnyRune(st *ParseState) (interface{}, error) {
+	return satisfyRune(func(*ParseState, rune) bool { return true }, nil)(st)
+}
+
+func Rune(r rune) Parser {
+	return satisfyRune(func(st *ParseState, x rune) bool { return st.sameRune(x, r) },
+		func(st *ParseState, x rune) ParseErr {
+			return st.trap("Expected '%c' but got '%c'", r, x)
+		})
+}
+
+func RuneOneOf(set string) Parser {
+	return satisfyRune(func(st *ParseState, r rune) bool { return st.runeIn(set, r) },
+		func(st *ParseState, r rune) ParseErr {
+			return st.trap("Expected one of '%s' but got '%c'", set, r)
+		})
+}
+
+func RuneNoneOf(set string) Parser {
+	return satisfyRune(func(st *ParseState, r rune) bool { return !st.runeIn(set, r) },
+		func(st *ParseState, r rune) ParseErr {
+			return st.trap("Unexpected '%c'", r)
+		})
+}
+
+// RuneIn matches a rune belonging to any of the given Unicode tables.
+func RuneIn(tables ...*unicode.RangeTable) Parser {
+	return satisfyRune(func(st *ParseState, r rune) bool { return unicode.IsOneOf(tables, r) },
+		func(st *ParseState, r rune) ParseErr {
+			return st.trap("Unexpected '%c'", r)
+		})
+}
@@ -0,0 +1,206 @@
+// Package searchquery parses Lucene and GitHub style search queries into a
+// boolean syntax tree. A query combines terms with NOT (or a leading -),
+// AND and OR, in decreasing order of precedence, and parentheses; terms
+// next to each other are implicitly joined by AND. A term is a word, a
+// "quoted phrase", or a field and a value, as in:
+//
+//	author:alice  title:"release notes"  stars:>=10  size:10..200
+//	date:[2020-01-01 TO *]  price:{0 TO 100]
+package searchquery
+
+import (
+	"strings"
+
+	"parsec"
+)
+
+type Kind int
+
+const (
+	Term Kind = iota
+	And
+	Or
+	Not
+)
+
+// Range is a value range. A bound of * is unbounded.
+type Range struct {
+	Lo, Hi               string
+	IncludeLo, IncludeHi bool
+}
+
+type Node struct {
+	Kind Kind
+	// Field is the field a term is restricted to, or "".
+	Field string
+	// Op is the comparison a term's value is used with: "", ">", ">=",
+	// "<" or "<=".
+	Op     string
+	Value  string
+	Phrase bool   // the value was quoted
+	Range  *Range // the term matches a range rather than Value
+	// Children holds the operands of And, Or and Not.
+	Children []*Node
+	Span     parsec.Span
+}
+
+var ws = parsec.SkipMany(parsec.OneOf([]byte(" \t\r\n")))
+
+func lexeme(p parsec.Parser) parsec.Parser {
+	return p.Bind(func(x interface{}) parsec.Parser {
+		return ws.Then(parsec.Return(x))
+	})
+}
+
+var keywords = map[string]bool{"AND": true, "OR": true, "NOT": true, "TO": true}
+
+// word parses a run of characters other than whitespace, parentheses,
+// quotes and colons. It fails without consuming input at a keyword.
+func word(st *parsec.ParseState) (interface{}, error) {
+	n := strings.IndexAny(st.Source[st.Pos:], " \t\r\n()\":[]{}")
+	if n < 0 {
+		n = len(st.Source) - st.Pos
+	}
+	w := st.Source[st.Pos : st.Pos+n]
+	if w == "" || keywords[w] {
+		return parsec.Fail("Expected term")(st)
+	}
+	st.Pos += n
+	return w, nil
+}
+
+var phraseChars = parsec.Many(parsec.Try(parsec.Char('\\').Then(parsec.OneOf([]byte(`"\`)))).Or(parsec.NoneOf([]byte(`"`)))).ToString()
+
+// phrase parses a quoted phrase, in which \" and \\ are escapes.
+func phrase(st *parsec.ParseState) (interface{}, error) {
+	start := st.Position()
+	if _, err := parsec.Char('"')(st); err != nil {
+		return nil, err
+	}
+	x, err := phraseChars(st)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := parsec.Char('"')(st); err != nil {
+		return nil, st.ErrorAt(start, "Unterminated phrase")
+	}
+	return x, nil
+}
+
+func keyword(k string) parsec.Parser {
+	return func(st *parsec.ParseState) (interface{}, error) {
+		rest := st.Source[st.Pos:]
+		if !strings.HasPrefix(rest, k) || len(rest) > len(k) && strings.IndexByte(" \t\r\n()\"", rest[len(k)]) < 0 {
+			return parsec.Fail("Expected " + k)(st)
+		}
+		st.Pos += len(k)
+		return k, nil
+	}
+}
+
+// rangeValue parses [lo TO hi], where either bracket may be a brace to
+// exclude that bound.
+var rangeValue = parsec.OneOf([]byte("[{")).Bind(func(open interface{}) parsec.Parser {
+	value := lexeme(parsec.Parser(phrase).Or(word))
+	return ws.Then(value).Bind(func(lo interface{}) parsec.Parser {
+		return lexeme(keyword("TO")).Then(value).Bind(func(hi interface{}) parsec.Parser {
+			return parsec.OneOf([]byte("]}")).Bind(func(close interface{}) parsec.Parser {
+				return parsec.Return(&Range{Lo: lo.(string), Hi: hi.(string), IncludeLo: open.(byte) == '[', IncludeHi: close.(byte) == ']'})
+			})
+		})
+	})
+})
+
+var comparison = parsec.String(">=").Or(parsec.String("<=")).Or(parsec.String(">")).Or(parsec.String("<"))
+
+// fieldValue parses the value following field:.
+func fieldValue(field string) parsec.Parser {
+	term := func(n *Node) parsec.Parser {
+		n.Field = field
+		return parsec.Return(n)
+	}
+	return rangeValue.Bind(func(r interface{}) parsec.Parser {
+		return term(&Node{Kind: Term, Range: r.(*Range)})
+	}).Or(parsec.Parser(phrase).Bind(func(p interface{}) parsec.Parser {
+		return term(&Node{Kind: Term, Value: p.(string), Phrase: true})
+	})).Or(comparison.Bind(func(op interface{}) parsec.Parser {
+		return parsec.Parser(word).Bind(func(w interface{}) parsec.Parser {
+			return term(&Node{Kind: Term, Op: op.(string), Value: w.(string)})
+		})
+	})).Or(parsec.Parser(word).Bind(func(x interface{}) parsec.Parser {
+		w := x.(string)
+		if lo, hi, ok := strings.Cut(w, ".."); ok {
+			return term(&Node{Kind: Term, Range: &Range{Lo: lo, Hi: hi, IncludeLo: true, IncludeHi: true}})
+		}
+		return term(&Node{Kind: Term, Value: w})
+	})).Label("value")
+}
+
+var term = parsec.Parser(phrase).Bind(func(p interface{}) parsec.Parser {
+	return parsec.Return(&Node{Kind: Term, Value: p.(string), Phrase: true})
+}).Or(parsec.Parser(word).Bind(func(w interface{}) parsec.Parser {
+	return parsec.Char(':').Then(fieldValue(w.(string))).
+		Or(parsec.Return(&Node{Kind: Term, Value: w.(string)}))
+}))
+
+func binary(kind Kind) func(x, y interface{}) interface{} {
+	return func(x, y interface{}) interface{} {
+		l, r := x.(*Node), y.(*Node)
+		return &Node{Kind: kind, Children: []*Node{l, r}, Span: parsec.Span{Start: l.Span.Start, End: r.Span.End}}
+	}
+}
+
+// implicitAnd succeeds without consuming input where another operand
+// follows directly.
+func implicitAnd(st *parsec.ParseState) (interface{}, error) {
+	rest := st.Source[st.Pos:]
+	if rest == "" || rest[0] == ')' || strings.HasPrefix(rest, "OR") && (len(rest) == 2 || strings.IndexByte(" \t\r\n(\"", rest[2]) >= 0) {
+		return parsec.Fail("Expected term")(st)
+	}
+	return binary(And), nil
+}
+
+func not(st *parsec.ParseState) (interface{}, error) {
+	start := st.Position()
+	if _, err := lexeme(keyword("NOT")).Or(parsec.Char('-'))(st); err != nil {
+		return nil, err
+	}
+	return func(x interface{}) interface{} {
+		n := x.(*Node)
+		return &Node{Kind: Not, Children: []*Node{n}, Span: parsec.Span{Start: start, End: n.Span.End}}
+	}, nil
+}
+
+var table = [][]parsec.Operator{
+	{{Fixity: parsec.Prefix, Op: not}},
+	{{Fixity: parsec.InfixLeft, Op: lexeme(keyword("AND")).Then(parsec.Return(binary(And)))}, {Fixity: parsec.InfixLeft, Op: implicitAnd}},
+	{{Fixity: parsec.InfixLeft, Op: lexeme(keyword("OR")).Then(parsec.Return(binary(Or)))}},
+}
+
+// Query parses a query followed by any whitespace and returns a *Node.
+var Query parsec.Parser
+
+func init() {
+	queryRef := parsec.Parser(func(st *parsec.ParseState) (interface{}, error) {
+		return Query(st)
+	})
+	group := queryRef.Between(lexeme(parsec.Char('(')), parsec.Char(')').Label("')'"))
+	operand := group.Or(term).Label("term").WithSpan().Bind(func(x interface{}) parsec.Parser {
+		s := x.(parsec.Spanned)
+		n := s.Value.(*Node)
+		n.Span = s.Span
+		return parsec.Return(n)
+	})
+	Query = parsec.BuildExpression(table, lexeme(operand))
+}
+
+// Parse parses a query. An empty query yields a nil node.
+func Parse(source string) (*Node, error) {
+	x, err := ws.Then(Query.Or(parsec.Return((*Node)(nil)))).Bind(func(x interface{}) parsec.Parser {
+		return parsec.Parser(parsec.Eof).Then(parsec.Return(x))
+	}).Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	return x.(*Node), nil
+}
@@ -0,0 +1,149 @@
+package parsec
+
+import (
+	"strconv"
+	"strings"
+)
+
+type SemVer struct {
+	Major, Minor, Patch uint64
+	Prerelease          []string
+	Build               []string
+}
+
+func (v SemVer) String() string {
+	s := strconv.FormatUint(v.Major, 10) + "." + strconv.FormatUint(v.Minor, 10) + "." + strconv.FormatUint(v.Patch, 10)
+	if len(v.Prerelease) > 0 {
+		s += "-" + strings.Join(v.Prerelease, ".")
+	}
+	if len(v.Build) > 0 {
+		s += "+" + strings.Join(v.Build, ".")
+	}
+	return s
+}
+
+// Compare returns -1, 0 or +1 as v has lower, equal or higher precedence
+// than w. Build metadata does not affect precedence.
+func (v SemVer) Compare(w SemVer) int {
+	if c := compareUint(v.Major, w.Major); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Minor, w.Minor); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Patch, w.Patch); c != 0 {
+		return c
+	}
+	switch {
+	case len(v.Prerelease) == 0 && len(w.Prerelease) == 0:
+		return 0
+	case len(v.Prerelease) == 0:
+		return 1
+	case len(w.Prerelease) == 0:
+		return -1
+	}
+	for i := 0; i < len(v.Prerelease) && i < len(w.Prerelease); i++ {
+		a, b := v.Prerelease[i], w.Prerelease[i]
+		an, aerr := strconv.ParseUint(a, 10, 64)
+		bn, berr := strconv.ParseUint(b, 10, 64)
+		var c int
+		switch {
+		case aerr == nil && berr == nil:
+			c = compareUint(an, bn)
+		case aerr == nil:
+			c = -1
+		case berr == nil:
+			c = 1
+		default:
+			c = strings.Compare(a, b)
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return compareUint(uint64(len(v.Prerelease)), uint64(len(w.Prerelease)))
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func isIdentChar(c byte) bool {
+	return isDigit(c) || isLetter(c) || c == '-'
+}
+
+func (st *ParseState) semverNumber(component string) (uint64, error) {
+	pos, begin := st.Position(), st.Pos
+	if st.skipWhile(isDigit) == 0 {
+		return 0, st.trap("Expected %s version", component)
+	}
+	text := st.Source[begin:st.Pos]
+	if len(text) > 1 && text[0] == '0' {
+		return 0, st.trapAt(pos, "Leading zero in %s version", component)
+	}
+	n, err := strconv.ParseUint(text, 10, 64)
+	if err != nil {
+		return 0, st.trapAt(pos, "Version number %s out of range", text)
+	}
+	return n, nil
+}
+
+func (st *ParseState) semverIdentifiers(component string, numeric bool) ([]string, error) {
+	var ids []string
+	for {
+		pos, begin := st.Position(), st.Pos
+		if st.skipWhile(isIdentChar) == 0 {
+			return nil, st.trap("Expected %s identifier", component)
+		}
+		id := st.Source[begin:st.Pos]
+		if numeric && len(id) > 1 && id[0] == '0' && strings.Trim(id, "0123456789") == "" {
+			return nil, st.trapAt(pos, "Leading zero in numeric %s identifier", component)
+		}
+		ids = append(ids, id)
+		if _, ok := st.next(func(c byte) bool { return c == '.' }); !ok {
+			return ids, nil
+		}
+	}
+}
+
+// SemanticVersion parses a version in Semantic Versioning 2.0.0 syntax,
+// such as 1.0.0-rc.1+build.5, and returns a SemVer.
+func SemanticVersion(st *ParseState) (interface{}, error) {
+	var v SemVer
+	var err error
+	if st.atEnd(st.Pos) || !isDigit(st.Source[st.Pos]) {
+		return nil, st.trap("Expected version")
+	}
+	if v.Major, err = st.semverNumber("major"); err != nil {
+		return nil, err
+	}
+	if err = st.expectByte('.', "after major version"); err != nil {
+		return nil, err
+	}
+	if v.Minor, err = st.semverNumber("minor"); err != nil {
+		return nil, err
+	}
+	if err = st.expectByte('.', "after minor version"); err != nil {
+		return nil, err
+	}
+	if v.Patch, err = st.semverNumber("patch"); err != nil {
+		return nil, err
+	}
+	if _, ok := st.next(func(c byte) bool { return c == '-' }); ok {
+		if v.Prerelease, err = st.semverIdentifiers("pre-release", true); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := st.next(func(c byte) bool { return c == '+' }); ok {
+		if v.Build, err = st.semverIdentifiers("build", false); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
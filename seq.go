@@ -0,0 +1,53 @@
+package parsec
+
+import "iter"
+
+// ManySeq is Many yielding the results of p one at a time as it parses
+// them, instead of collecting them in a slice. A failure of p after
+// consuming input is yielded as the final error; one without consuming
+// input ends the sequence, leaving st where p failed. Stopping the range
+// early leaves st after the last result yielded.
+func ManySeq(p Parser) func(*ParseState) iter.Seq2[interface{}, error] {
+	return func(st *ParseState) iter.Seq2[interface{}, error] {
+		return func(yield func(interface{}, error) bool) {
+			for {
+				oldPos, oldBit := st.Pos, st.bit
+				x, err := p(st)
+				if err != nil {
+					if st.Pos != oldPos || st.bit != oldBit || st.cut {
+						yield(nil, err)
+					}
+					return
+				}
+				if !yield(x, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// ParseSeq parses source as a series of p to the end of input, yielding
+// each result as soon as it is parsed, for inputs too large to hold all of
+// their results at once. Input that p stops short of is yielded as a final
+// error.
+func (p Parser) ParseSeq(source string, opts ...ParseOption) iter.Seq2[interface{}, error] {
+	return func(yield func(interface{}, error) bool) {
+		st := ParseState{Source: source, Line: 1}
+		for _, opt := range opts {
+			opt(&st)
+		}
+		if st.inputErr != nil {
+			yield(nil, st.inputErr)
+			return
+		}
+		for x, err := range ManySeq(p)(&st) {
+			if !yield(x, err) || err != nil {
+				return
+			}
+		}
+		if _, err := Eof(&st); err != nil {
+			yield(nil, err)
+		}
+	}
+}
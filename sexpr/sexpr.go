@@ -0,0 +1,181 @@
+// Package sexpr parses S-expressions into a tree of positioned nodes. It
+// doubles as the canonical example of a recursive grammar.
+package sexpr
+
+import (
+	"strconv"
+	"strings"
+
+	"parsec"
+	"parsec/pretty"
+)
+
+type Kind int
+
+const (
+	Symbol Kind = iota
+	String
+	Integer
+	Float
+	List
+)
+
+type Node struct {
+	Kind Kind
+	// Value is a string for symbols and strings, an int64 for integers and
+	// a float64 for floats. It is nil for lists.
+	Value    interface{}
+	Children []*Node
+	Span     parsec.Span
+}
+
+// String formats the node as an S-expression. Quote sugar is written in
+// its long form.
+func (n *Node) String() string {
+	switch n.Kind {
+	case List:
+		parts := make([]string, len(n.Children))
+		for i, c := range n.Children {
+			parts[i] = c.String()
+		}
+		return "(" + strings.Join(parts, " ") + ")"
+	case String:
+		return strconv.Quote(n.Value.(string))
+	case Symbol:
+		return n.Value.(string)
+	case Integer:
+		return strconv.FormatInt(n.Value.(int64), 10)
+	}
+	// A float needs a point or an exponent to read back as one. Infinities
+	// are written +Inf and -Inf, and NaN as NaN, which atom reads as floats.
+	s := strconv.FormatFloat(n.Value.(float64), 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
+// Doc returns the node as a pretty.Doc, laying out a list that does not fit
+// on one line with its head on the first line and the other elements below
+// it, indented by two columns.
+func (n *Node) Doc() pretty.Doc {
+	if n.Kind != List {
+		return pretty.Text(n.String())
+	}
+	if len(n.Children) == 0 {
+		return pretty.Text("()")
+	}
+	if len(n.Children) == 1 {
+		return pretty.Concat(pretty.Text("("), n.Children[0].Doc(), pretty.Text(")"))
+	}
+	docs := make([]pretty.Doc, len(n.Children)-1)
+	for i, c := range n.Children[1:] {
+		docs[i] = c.Doc()
+	}
+	return pretty.Group(pretty.Concat(pretty.Text("("), n.Children[0].Doc(),
+		pretty.Nest(2, pretty.Concat(pretty.Line, pretty.Join(pretty.Line, docs))), pretty.Text(")")))
+}
+
+// Format formats nodes one per line, breaking lists to fit in width columns.
+func Format(nodes []*Node, width int) string {
+	docs := make([]pretty.Doc, len(nodes))
+	for i, n := range nodes {
+		docs[i] = n.Doc()
+	}
+	return pretty.Render(width, pretty.Concat(pretty.Join(pretty.HardLine, docs), pretty.HardLine))
+}
+
+var lineComment = parsec.Char(';').Then(parsec.SkipMany(parsec.NoneOf([]byte("\r\n"))))
+var blockComment = parsec.String("#|").Then(parsec.Skip(parsec.ManyTill(parsec.AnyChar, parsec.String("|#"))))
+var ws = parsec.SkipMany(parsec.OneOf([]byte(" \t\r\n\f")).Or(lineComment).Or(blockComment))
+
+var str = parsec.QuotedString(parsec.QuoteOptions{
+	Quotes:     `"`,
+	Escape:     '\\',
+	Escapes:    parsec.CEscapes,
+	HexEscapes: true,
+	Multiline:  true,
+})
+
+// atom parses a symbol or number: a run of characters other than
+// whitespace and delimiters, classified as a number if it reads as one.
+// Words such as inf are symbols; only the signed +Inf and -Inf, and NaN,
+// are taken as floats.
+func atom(st *parsec.ParseState) (interface{}, error) {
+	n := strings.IndexAny(st.Source[st.Pos:], " \t\r\n\f()\"';`,")
+	if n < 0 {
+		n = len(st.Source) - st.Pos
+	}
+	if n == 0 {
+		return parsec.Fail("Expected expression")(st)
+	}
+	text := st.Source[st.Pos : st.Pos+n]
+	st.Pos += n
+	if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return &Node{Kind: Integer, Value: i}, nil
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil && (text == "NaN" || strings.IndexAny(text[:1], "0123456789+-.") == 0) {
+		return &Node{Kind: Float, Value: f}, nil
+	}
+	return &Node{Kind: Symbol, Value: text}, nil
+}
+
+var sugar = []struct{ prefix, symbol string }{
+	{"'", "quote"},
+	{"`", "quasiquote"},
+	{",@", "unquote-splicing"},
+	{",", "unquote"},
+}
+
+// Expression parses one S-expression followed by any whitespace and
+// comments, and returns a *Node.
+var Expression parsec.Parser
+
+func init() {
+	exprRef := parsec.Parser(func(st *parsec.ParseState) (interface{}, error) {
+		return Expression(st)
+	})
+	list := ws.Then(parsec.Many(exprRef)).Between(parsec.Char('('), parsec.Char(')').Label("')'")).Bind(func(x interface{}) parsec.Parser {
+		n := &Node{Kind: List, Children: []*Node{}}
+		for _, c := range x.([]interface{}) {
+			n.Children = append(n.Children, c.(*Node))
+		}
+		return parsec.Return(n)
+	})
+	quoted := func(prefix, symbol string) parsec.Parser {
+		return parsec.String(prefix).WithSpan().Bind(func(p interface{}) parsec.Parser {
+			return ws.Then(exprRef).Bind(func(x interface{}) parsec.Parser {
+				sym := &Node{Kind: Symbol, Value: symbol, Span: p.(parsec.Spanned).Span}
+				return parsec.Return(&Node{Kind: List, Children: []*Node{sym, x.(*Node)}})
+			})
+		})
+	}
+	expr := list.Or(str.Bind(func(x interface{}) parsec.Parser {
+		return parsec.Return(&Node{Kind: String, Value: x})
+	}))
+	for _, s := range sugar {
+		expr = expr.Or(quoted(s.prefix, s.symbol))
+	}
+	expr = expr.Or(atom).Label("expression").WithSpan().Bind(func(x interface{}) parsec.Parser {
+		s := x.(parsec.Spanned)
+		n := s.Value.(*Node)
+		n.Span = s.Span
+		return ws.Then(parsec.Return(n))
+	})
+	Expression = expr
+}
+
+// Parse parses a sequence of S-expressions.
+func Parse(source string) ([]*Node, error) {
+	x, err := ws.Then(parsec.Many(Expression)).Bind(func(x interface{}) parsec.Parser {
+		return parsec.Parser(parsec.Eof).Then(parsec.Return(x))
+	}).Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	var nodes []*Node
+	for _, n := range x.([]interface{}) {
+		nodes = append(nodes, n.(*Node))
+	}
+	return nodes, nil
+}
@@ -0,0 +1,169 @@
+// Package shellwords splits command lines into words following the POSIX
+// shell's quoting rules: single quotes preserve their contents, double
+// quotes preserve all but \$, \`, \", \\ and escaped line breaks, and an
+// unquoted backslash preserves the next character. A # at the start of a
+// word begins a comment. Operators such as | and ; are not recognized and
+// expansions are not performed.
+package shellwords
+
+import (
+	"strings"
+
+	"parsec"
+)
+
+// Options configures Grammar.
+type Options struct {
+	// Variables records the $NAME, ${NAME} and special parameter
+	// references found outside single quotes. Their text is kept in the
+	// word's value.
+	Variables bool
+}
+
+type Var struct {
+	Name string
+	Span parsec.Span
+}
+
+type Word struct {
+	Value string
+	Span  parsec.Span
+	Vars  []Var
+}
+
+type varRef struct {
+	Var
+	text string
+}
+
+var continuation = parsec.Try(parsec.Char('\\').Then(parsec.Newline)).Then(parsec.Return(""))
+var escaped = parsec.Char('\\').Then(parsec.AnyChar)
+
+var separator = parsec.SkipMany(parsec.OneOf([]byte(" \t\r\n")).
+	Or(continuation).
+	Or(parsec.Char('#').Then(parsec.SkipMany(parsec.NoneOf([]byte("\r\n"))))))
+
+func isNameChar(c byte, first bool) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '_' || !first && '0' <= c && c <= '9'
+}
+
+// variable parses a parameter reference, failing without consuming input
+// if the $ does not start one.
+func variable(st *parsec.ParseState) (interface{}, error) {
+	start, s := st.Position(), st.Source[st.Pos:]
+	if len(s) < 2 || s[0] != '$' {
+		return parsec.Fail("Expected variable")(st)
+	}
+	n := 0
+	switch {
+	case s[1] == '{':
+		end := strings.IndexByte(s, '}')
+		if end < 0 {
+			// Consumed, so that the choice of pieces does not move on.
+			st.Pos += 2
+			return nil, st.ErrorAt(start, "Unterminated ${")
+		}
+		n = end + 1
+	case isNameChar(s[1], true):
+		for n = 2; n < len(s) && isNameChar(s[n], false); n++ {
+		}
+	case strings.IndexByte("0123456789?$!#*@-", s[1]) >= 0:
+		n = 2
+	default:
+		return parsec.Fail("Expected variable")(st)
+	}
+	st.Pos += n
+	name := strings.TrimSuffix(strings.TrimPrefix(s[1:n], "{"), "}")
+	return varRef{Var{Name: name, Span: parsec.Span{Start: start, End: st.Position()}}, s[:n]}, nil
+}
+
+// quoted parses a string between quote characters, reporting an
+// unterminated string at its opening quote.
+func quoted(quote byte, what string, inner parsec.Parser) parsec.Parser {
+	return func(st *parsec.ParseState) (interface{}, error) {
+		start := st.Position()
+		if _, err := parsec.Char(quote)(st); err != nil {
+			return nil, err
+		}
+		x, err := parsec.Many(inner)(st)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := parsec.Char(quote)(st); err != nil {
+			return nil, st.ErrorAt(start, "Unterminated %s string", what)
+		}
+		return x, nil
+	}
+}
+
+// Grammar returns a parser for a command line, returning a []Word.
+func Grammar(opts Options) parsec.Parser {
+	dollar := parsec.Parser(parsec.Fail("Expected variable"))
+	if opts.Variables {
+		dollar = variable
+	}
+	single := quoted('\'', "single-quoted", parsec.NoneOf([]byte("'")))
+	double := quoted('"', "double-quoted", continuation.
+		Or(parsec.Try(parsec.Char('\\').Then(parsec.OneOf([]byte("$`\"\\"))))).
+		Or(dollar).
+		Or(parsec.NoneOf([]byte(`"`))))
+	piece := continuation.
+		Or(escaped).
+		Or(single).
+		Or(double).
+		Or(dollar).
+		Or(parsec.NoneOf([]byte(" \t\r\n'\"\\")))
+	word := parsec.Many1(piece).WithSpan().Bind(func(x interface{}) parsec.Parser {
+		s := x.(parsec.Spanned)
+		w := Word{Span: s.Span}
+		var sb strings.Builder
+		var add func(interface{})
+		add = func(p interface{}) {
+			switch p := p.(type) {
+			case byte:
+				sb.WriteByte(p)
+			case string:
+				sb.WriteString(p)
+			case varRef:
+				sb.WriteString(p.text)
+				w.Vars = append(w.Vars, p.Var)
+			case []interface{}:
+				for _, q := range p {
+					add(q)
+				}
+			}
+		}
+		add(s.Value)
+		w.Value = sb.String()
+		return parsec.Return(w)
+	})
+	return separator.Then(parsec.Many(word.Bind(func(x interface{}) parsec.Parser {
+		return separator.Then(parsec.Return(x))
+	}))).Bind(func(x interface{}) parsec.Parser {
+		words := []Word{}
+		for _, w := range x.([]interface{}) {
+			words = append(words, w.(Word))
+		}
+		return parsec.Parser(parsec.Eof).Then(parsec.Return(words))
+	})
+}
+
+var grammar = Grammar(Options{})
+
+// Split splits a command line into words.
+func Split(line string) ([]Word, error) {
+	x, err := grammar.Parse(line)
+	if err != nil {
+		return nil, err
+	}
+	return x.([]Word), nil
+}
+
+// Strings returns the values of words.
+func Strings(words []Word) []string {
+	values := make([]string, len(words))
+	for i, w := range words {
+		values[i] = w.Value
+	}
+	return values
+}
@@ -0,0 +1,56 @@
+package parsec
+
+import (
+	"math/big"
+	"strings"
+)
+
+var sizeUnits = map[string]int64{
+	"b": 1,
+	"k": 1e3, "kb": 1e3, "m": 1e6, "mb": 1e6, "g": 1e9, "gb": 1e9,
+	"t": 1e12, "tb": 1e12, "p": 1e15, "pb": 1e15, "e": 1e18, "eb": 1e18,
+	"ki": 1 << 10, "kib": 1 << 10, "mi": 1 << 20, "mib": 1 << 20, "gi": 1 << 30, "gib": 1 << 30,
+	"ti": 1 << 40, "tib": 1 << 40, "pi": 1 << 50, "pib": 1 << 50, "ei": 1 << 60, "eib": 1 << 60,
+}
+
+// ByteSize parses a size such as 512, 10MB, 1.5k or 4GiB and returns the
+// number of bytes as an int64. Decimal units (k, MB, ...) are powers of 1000
+// and binary units (Ki, MiB, ...) powers of 1024; units are matched
+// case-insensitively and may be separated from the number by a space.
+func ByteSize(st *ParseState) (interface{}, error) {
+	start, begin := st.Position(), st.Pos
+	st.skipWhile(isDigit)
+	if rest := st.peek(2); len(rest) > 1 && rest[0] == '.' && isDigit(rest[1]) {
+		st.Pos++
+		st.skipWhile(isDigit)
+	}
+	if st.Pos == begin {
+		return nil, st.trap("Expected size")
+	}
+	n, _ := new(big.Rat).SetString(st.Source[begin:st.Pos])
+	mark := st.Pos
+	st.next(func(c byte) bool { return c == ' ' })
+	unit := ""
+	for i := st.Pos; i-st.Pos < 3 && !st.atEnd(i) && isLetter(st.Source[i]); i++ {
+		if _, ok := sizeUnits[strings.ToLower(st.Source[st.Pos:i+1])]; ok {
+			unit = st.Source[st.Pos : i+1]
+		}
+	}
+	if unit == "" {
+		st.Pos = mark
+	} else {
+		st.Pos += len(unit)
+		n.Mul(n, new(big.Rat).SetInt64(sizeUnits[strings.ToLower(unit)]))
+	}
+	if !n.IsInt() {
+		return nil, st.trapAt(start, "Size %s is not a whole number of bytes", st.Source[begin:st.Pos])
+	}
+	if !n.Num().IsInt64() {
+		return nil, st.trapAt(start, "Size %s out of range", st.Source[begin:st.Pos])
+	}
+	return n.Num().Int64(), nil
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
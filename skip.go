@@ -0,0 +1,60 @@
+package parsec
+
+import "strings"
+
+// SkipUntil skips input up to the next occurrence of delim, leaving delim
+// itself unconsumed, as when ignoring everything before the next section
+// header. It fails without consuming input if delim does not occur.
+func SkipUntil(delim string) Parser {
+	match := String(delim)
+	return func(st *ParseState) (interface{}, error) {
+		if !st.fold {
+			if n := strings.Index(st.Source[st.Pos:], delim); n >= 0 {
+				st.advance(n)
+				return nil, nil
+			}
+			st.sawEnd = true
+			return nil, st.trap("Expected '%s'", delim)
+		}
+		oldPos, oldLine := st.Pos, st.Line
+		for {
+			pos, line := st.Pos, st.Line
+			if _, err := match(st); err == nil {
+				st.Pos, st.Line = pos, line
+				return nil, nil
+			}
+			if st.Pos >= len(st.Source) {
+				st.Pos, st.Line = oldPos, oldLine
+				st.sawEnd = true
+				return nil, st.trap("Expected '%s'", delim)
+			}
+			st.advance(1)
+		}
+	}
+}
+
+// SkipTill skips input up to the first position where p succeeds and
+// returns p's result, without collecting the input skipped the way ManyTill
+// would. If p succeeds nowhere, SkipTill fails with p's error at the end of
+// input, without consuming anything.
+func SkipTill(p Parser) Parser {
+	p = Try(p)
+	return func(st *ParseState) (interface{}, error) {
+		oldPos, oldLine := st.Pos, st.Line
+		for {
+			x, err := p(st)
+			if err == nil {
+				return x, nil
+			}
+			if st.cut {
+				return nil, err
+			}
+			if st.Pos >= len(st.Source) {
+				st.Pos, st.Line = oldPos, oldLine
+				st.sawEnd = true
+				return nil, err
+			}
+			st.advance(1)
+		}
+	}
+}
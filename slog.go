@@ -0,0 +1,46 @@
+package parsec
+
+import (
+	"context"
+	"log/slog"
+)
+
+type slogHooks struct {
+	logger *slog.Logger
+	level  slog.Level
+}
+
+// SlogHooks returns Hooks that log every labeled parser's entry and exit to
+// logger at the given level, with rule, offset, line and outcome attributes.
+func SlogHooks(logger *slog.Logger, level slog.Level) Hooks {
+	return slogHooks{logger: logger, level: level}
+}
+
+func (h slogHooks) OnEnter(name string, pos Position) {
+	ctx := context.Background()
+	if !h.logger.Enabled(ctx, h.level) {
+		return
+	}
+	h.logger.LogAttrs(ctx, h.level, "parser enter",
+		slog.String("rule", name),
+		slog.Int("offset", pos.Offset),
+		slog.Int("line", pos.Line))
+}
+
+func (h slogHooks) OnExit(name string, pos Position, result interface{}, err error) {
+	ctx := context.Background()
+	if !h.logger.Enabled(ctx, h.level) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("rule", name),
+		slog.Int("offset", pos.Offset),
+		slog.Int("line", pos.Line),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("outcome", "failure"), slog.String("error", err.Error()))
+	} else {
+		attrs = append(attrs, slog.String("outcome", "success"))
+	}
+	h.logger.LogAttrs(ctx, h.level, "parser exit", attrs...)
+}
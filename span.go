@@ -0,0 +1,34 @@
+package parsec
+
+type Position struct {
+	Offset int `json:"offset"`
+	Line   int `json:"line"`
+}
+
+type Span struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+func (st *ParseState) Position() Position {
+	return Position{Offset: st.originOffset(st.Pos), Line: st.Line}
+}
+
+// Spanned is the result of a parser wrapped by WithSpan.
+type Spanned struct {
+	Value interface{}
+	Span  Span
+}
+
+// WithSpan returns a parser yielding p's result together with the span of
+// input it consumed.
+func (p Parser) WithSpan() Parser {
+	return func(st *ParseState) (interface{}, error) {
+		start := st.Position()
+		x, err := p(st)
+		if err != nil {
+			return nil, err
+		}
+		return Spanned{Value: x, Span: Span{Start: start, End: st.Position()}}, nil
+	}
+}
@@ -0,0 +1,73 @@
+package parsec
+
+import (
+	"errors"
+	"io"
+)
+
+// streamChunk is the size of the reads made by Feeder.Stream.
+const streamChunk = 64 << 10
+
+// ParseStreamFunc reads r to the end as a series of p, such as the lines or
+// records of a log, and calls fn with each result and its span as soon as
+// it is parsed. Only the input of the record being parsed is held in
+// memory, so p must not need more than one record at a time; the Feeder
+// conventions for parsers apply. An error from fn stops the parse and is
+// returned.
+func (p Parser) ParseStreamFunc(r io.Reader, fn func(Spanned) error) error {
+	return NewFeeder(p).Stream(r, fn)
+}
+
+// Stream is ParseStreamFunc for the Feeder's parser, continuing from the
+// Feeder's state, as when resuming from a Checkpoint.
+func (f *Feeder) Stream(r io.Reader, fn func(Spanned) error) error {
+	emit := func() error {
+		for {
+			start := f.Position()
+			x, ok, err := f.Next()
+			if err != nil || !ok {
+				return err
+			}
+			if err := fn(Spanned{Value: x, Span: Span{Start: start, End: f.Position()}}); err != nil {
+				return err
+			}
+		}
+	}
+	buf := make([]byte, streamChunk)
+	for {
+		n, rerr := r.Read(buf)
+		f.Append(buf[:n])
+		if err := emit(); err != nil {
+			return err
+		}
+		if errors.Is(rerr, io.EOF) {
+			f.closed = true
+			return emit()
+		}
+		if rerr != nil {
+			return rerr
+		}
+	}
+}
+
+// ParseStream is ParseStreamFunc delivering results on a channel from a
+// goroutine of its own. The results channel is closed when r is exhausted
+// or the parse fails, and the error channel then receives the error, if
+// any, and is closed. The caller must receive every result, or the
+// goroutine never finishes.
+func (p Parser) ParseStream(r io.Reader) (<-chan Spanned, <-chan error) {
+	results := make(chan Spanned)
+	errc := make(chan error, 1)
+	go func() {
+		err := p.ParseStreamFunc(r, func(x Spanned) error {
+			results <- x
+			return nil
+		})
+		close(results)
+		if err != nil {
+			errc <- err
+		}
+		close(errc)
+	}()
+	return results, errc
+}
@@ -0,0 +1,258 @@
+// Package syslog parses syslog messages in the RFC 5424 format and,
+// optionally, the legacy BSD format described by RFC 3164.
+package syslog
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"parsec"
+)
+
+type Param struct {
+	Name, Value string
+}
+
+// Element is an element of structured data, such as
+// [exampleSDID@32473 iut="3" eventSource="Application"].
+type Element struct {
+	ID     string
+	Params []Param
+}
+
+// Message is a parsed syslog message. Fields given as the nil value - are
+// empty, and a nil timestamp is the zero time.
+type Message struct {
+	Facility int
+	Severity int
+	// Version is 1 for RFC 5424 messages and 0 for legacy messages.
+	Version        int
+	Timestamp      time.Time
+	Hostname       string
+	AppName        string
+	ProcID         string
+	MsgID          string
+	StructuredData []Element
+	Message        string
+}
+
+// Options configures Grammar.
+type Options struct {
+	// Lenient accepts messages in the RFC 3164 format as well:
+	// <PRI>Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG. The tag becomes the
+	// AppName and the PID the ProcID.
+	Lenient bool
+	// Year is the year of legacy timestamps, which omit it. Defaults to
+	// the current year.
+	Year int
+}
+
+var sp = parsec.Char(' ').Label("space")
+
+// pri parses <PRI>, returning the facility and severity.
+func pri(st *parsec.ParseState) (interface{}, error) {
+	start := st.Position()
+	if _, err := parsec.Char('<')(st); err != nil {
+		return nil, err
+	}
+	x, err := parsec.Many1(parsec.Digit).ToString()(st)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := parsec.Char('>')(st); err != nil {
+		return nil, err
+	}
+	v, err := strconv.Atoi(x.(string))
+	if err != nil || len(x.(string)) > 3 || v > 191 {
+		return nil, st.ErrorAt(start, "Invalid PRI %s", x)
+	}
+	return [2]int{v / 8, v % 8}, nil
+}
+
+var nilValue = parsec.Char('-').Then(parsec.Return(""))
+
+// field parses a header field of printable ASCII characters up to max
+// long, or the nil value.
+func field(name string, max int) parsec.Parser {
+	return func(st *parsec.ParseState) (interface{}, error) {
+		start, rest := st.Position(), st.Source[st.Pos:]
+		n := strings.IndexFunc(rest, func(r rune) bool { return r < 33 || r > 126 })
+		if n < 0 {
+			n = len(rest)
+		}
+		if n == 0 {
+			return parsec.Fail("Expected " + name)(st)
+		}
+		if n > max {
+			return nil, st.ErrorAt(start, "%s longer than %d characters", name, max)
+		}
+		st.Pos += n
+		if rest[:n] == "-" {
+			return "", nil
+		}
+		return rest[:n], nil
+	}
+}
+
+var timestamp = nilValue.Or(parsec.Parser(parsec.Timestamp)).Label("timestamp")
+
+// sdName parses an SD-ID or PARAM-NAME.
+func sdName(what string) parsec.Parser {
+	return parsec.Many1(parsec.NoneOf([]byte(" =]\"\r\n\t"))).ToString().Label(what)
+}
+
+// paramValue parses a quoted parameter value, in which \", \\ and \] are
+// escapes and other backslashes are literal.
+var paramValue = parsec.Many(parsec.Try(parsec.Char('\\').Then(parsec.OneOf([]byte(`"\]`)))).
+	Or(parsec.NoneOf([]byte(`"`)))).ToString().
+	Between(parsec.Char('"'), parsec.Char('"').Label("closing '\"'"))
+
+var param = sdName("PARAM-NAME").Bind(func(name interface{}) parsec.Parser {
+	return parsec.Char('=').Then(paramValue).Bind(func(v interface{}) parsec.Parser {
+		return parsec.Return(Param{name.(string), v.(string)})
+	})
+})
+
+var element = parsec.Char('[').Then(sdName("SD-ID")).Bind(func(id interface{}) parsec.Parser {
+	return parsec.Many(sp.Then(param)).Bind(func(x interface{}) parsec.Parser {
+		e := Element{ID: id.(string)}
+		for _, p := range x.([]interface{}) {
+			e.Params = append(e.Params, p.(Param))
+		}
+		return parsec.Char(']').Label("']'").Then(parsec.Return(e))
+	})
+})
+
+var structuredData = nilValue.Then(parsec.Return([]Element(nil))).Or(parsec.Many1(element).Bind(func(x interface{}) parsec.Parser {
+	var elems []Element
+	for _, e := range x.([]interface{}) {
+		elems = append(elems, e.(Element))
+	}
+	return parsec.Return(elems)
+})).Label("structured data")
+
+// rest returns the remainder of the message, without a UTF-8 byte order
+// mark.
+func rest(st *parsec.ParseState) (interface{}, error) {
+	msg := strings.TrimPrefix(st.Source[st.Pos:], "\xef\xbb\xbf")
+	st.Pos = len(st.Source)
+	return strings.TrimRight(msg, "\r\n"), nil
+}
+
+// rfc5424 parses a message after its PRI.
+func rfc5424(m *Message) parsec.Parser {
+	set := func(dst *string) func(interface{}) parsec.Parser {
+		return func(x interface{}) parsec.Parser {
+			*dst = x.(string)
+			return sp
+		}
+	}
+	version := func(st *parsec.ParseState) (interface{}, error) {
+		start := st.Position()
+		x, err := parsec.Many1(parsec.Digit).ToString().Label("version")(st)
+		if err != nil {
+			return nil, err
+		}
+		v, _ := strconv.Atoi(x.(string))
+		if v < 1 || v > 99 {
+			return nil, st.ErrorAt(start, "Invalid version %s", x)
+		}
+		m.Version = v
+		return sp(st)
+	}
+	return parsec.Parser(version).Then(timestamp).Bind(func(x interface{}) parsec.Parser {
+		if t, ok := x.(time.Time); ok {
+			m.Timestamp = t
+		}
+		return sp
+	}).Then(field("HOSTNAME", 255)).Bind(set(&m.Hostname)).
+		Then(field("APP-NAME", 48)).Bind(set(&m.AppName)).
+		Then(field("PROCID", 128)).Bind(set(&m.ProcID)).
+		Then(field("MSGID", 32)).Bind(set(&m.MsgID)).
+		Then(structuredData).Bind(func(x interface{}) parsec.Parser {
+		m.StructuredData = x.([]Element)
+		return sp.Then(rest).Or(parsec.Eol.Then(parsec.Return("")))
+	}).Bind(func(x interface{}) parsec.Parser {
+		m.Message = x.(string)
+		return parsec.Return(m)
+	})
+}
+
+// rfc3164 parses a legacy message after its PRI.
+func rfc3164(m *Message, year int) parsec.Parser {
+	return func(st *parsec.ParseState) (interface{}, error) {
+		s := st.Source[st.Pos:]
+		if len(s) < 16 || s[15] != ' ' {
+			return nil, st.ErrorAt(st.Position(), "Expected timestamp")
+		}
+		t, err := time.Parse("Jan _2 15:04:05", s[:15])
+		if err != nil {
+			return nil, st.ErrorAt(st.Position(), "Invalid timestamp %s", s[:15])
+		}
+		m.Timestamp = t.AddDate(year, 0, 0)
+		st.Pos += 16
+		host, err := field("HOSTNAME", 255)(st)
+		if err != nil {
+			return nil, err
+		}
+		m.Hostname = host.(string)
+		if _, err := sp(st); err != nil {
+			return nil, err
+		}
+		s = st.Source[st.Pos:]
+		if n := strings.IndexAny(s, "[: "); n > 0 && n <= 32 {
+			m.AppName, s = s[:n], s[n:]
+			if end := strings.IndexByte(s, ']'); s[0] == '[' && end > 0 {
+				m.ProcID, s = s[1:end], s[end+1:]
+			}
+			s = strings.TrimPrefix(s, ":")
+			s = strings.TrimPrefix(s, " ")
+		}
+		st.Pos = len(st.Source) - len(s)
+		return parsec.Parser(rest).Bind(func(x interface{}) parsec.Parser {
+			m.Message = x.(string)
+			return parsec.Return(m)
+		})(st)
+	}
+}
+
+// Grammar returns a parser for a single message, which yields a *Message.
+func Grammar(opts Options) parsec.Parser {
+	return func(st *parsec.ParseState) (interface{}, error) {
+		m := &Message{}
+		x, err := pri(st)
+		if err != nil {
+			return nil, err
+		}
+		m.Facility, m.Severity = x.([2]int)[0], x.([2]int)[1]
+		if s := st.Source[st.Pos:]; opts.Lenient && (s == "" || s[0] < '1' || s[0] > '9') {
+			year := opts.Year
+			if year == 0 {
+				year = time.Now().Year()
+			}
+			return rfc3164(m, year)(st)
+		}
+		return rfc5424(m)(st)
+	}
+}
+
+var strict = Grammar(Options{})
+
+// Parse parses an RFC 5424 message.
+func Parse(msg string) (*Message, error) {
+	return parse(strict, msg)
+}
+
+// ParseWith parses a message as configured by opts.
+func ParseWith(msg string, opts Options) (*Message, error) {
+	return parse(Grammar(opts), msg)
+}
+
+func parse(p parsec.Parser, msg string) (*Message, error) {
+	x, err := p.Parse(msg)
+	if err != nil {
+		return nil, err
+	}
+	return x.(*Message), nil
+}
@@ -0,0 +1,112 @@
+// Package template splits text containing {{ ... }} interpolations into
+// literal text and actions, leaving the contents of actions to be parsed
+// by another grammar. Braces inside an action must balance, and braces
+// inside quoted strings are ignored, so {{ f("}}") }} is a single action.
+package template
+
+import (
+	"strings"
+
+	"parsec"
+)
+
+type Kind int
+
+const (
+	Literal Kind = iota
+	Action
+)
+
+type Segment struct {
+	Kind Kind
+	// Text is the literal text, or the action's contents without the
+	// delimiters and surrounding whitespace.
+	Text string
+	Span parsec.Span
+}
+
+// literal parses the text up to the next {{ or the end of input.
+func literal(st *parsec.ParseState) (interface{}, error) {
+	n := strings.Index(st.Source[st.Pos:], "{{")
+	if n < 0 {
+		n = len(st.Source) - st.Pos
+	}
+	if n == 0 {
+		return parsec.Fail("Expected text")(st)
+	}
+	begin := st.Pos
+	// Consume byte by byte so that line numbers are kept.
+	for st.Pos < begin+n {
+		parsec.AnyChar(st)
+	}
+	return Segment{Kind: Literal, Text: st.Source[begin:st.Pos]}, nil
+}
+
+func quoted(q byte) parsec.Parser {
+	return parsec.Many(parsec.Char('\\').Then(parsec.AnyChar).Or(parsec.NoneOf([]byte{q, '\\'}))).
+		Between(parsec.Char(q), parsec.Char(q).Label("closing "+string(q)))
+}
+
+var str = quoted('"').Or(quoted('\'')).Or(parsec.RawString("`", "`"))
+
+// loneBrace parses a } that does not close the action.
+func loneBrace(st *parsec.ParseState) (interface{}, error) {
+	if strings.HasPrefix(st.Source[st.Pos:], "}}") {
+		return parsec.Fail("Unexpected '}}'")(st)
+	}
+	return parsec.Char('}')(st)
+}
+
+var nested parsec.Parser
+
+func nestedRef(st *parsec.ParseState) (interface{}, error) {
+	return nested(st)
+}
+
+var other = parsec.NoneOf([]byte("{}\"'`"))
+
+func init() {
+	nested = parsec.SkipMany(parsec.Parser(nestedRef).Or(str).Or(other)).Between(parsec.Char('{'), parsec.Char('}').Label("'}'"))
+}
+
+var body = parsec.SkipMany(parsec.Parser(nestedRef).Or(str).Or(other).Or(loneBrace))
+
+// action parses {{ ... }}, reporting an unterminated action at its start.
+func action(st *parsec.ParseState) (interface{}, error) {
+	start := st.Position()
+	if _, err := parsec.String("{{")(st); err != nil {
+		return nil, err
+	}
+	begin := st.Pos
+	if _, err := body(st); err != nil {
+		return nil, err
+	}
+	end := st.Pos
+	if _, err := parsec.String("}}")(st); err != nil {
+		return nil, st.ErrorAt(start, "Unterminated action")
+	}
+	return Segment{Kind: Action, Text: strings.TrimSpace(st.Source[begin:end])}, nil
+}
+
+// Grammar parses a template and returns a []Segment.
+var Grammar = parsec.Many(parsec.Parser(action).Or(literal).WithSpan().Bind(func(x interface{}) parsec.Parser {
+	s := x.(parsec.Spanned)
+	seg := s.Value.(Segment)
+	seg.Span = s.Span
+	return parsec.Return(seg)
+})).Bind(func(x interface{}) parsec.Parser {
+	segs := []Segment{}
+	for _, s := range x.([]interface{}) {
+		segs = append(segs, s.(Segment))
+	}
+	return parsec.Parser(parsec.Eof).Then(parsec.Return(segs))
+})
+
+// Parse parses a template.
+func Parse(source string) ([]Segment, error) {
+	x, err := Grammar.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	return x.([]Segment), nil
+}
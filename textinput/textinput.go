@@ -0,0 +1,60 @@
+// Package textinput provides parse options that convert the input with
+// golang.org/x/text before parsing, kept apart from parsec so that grammars
+// not using them do not depend on it. Errors and positions still refer to
+// the original input.
+package textinput
+
+import (
+	"fmt"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+
+	"parsec"
+)
+
+// WithNormalization converts the input to the given Unicode normalization
+// form (usually norm.NFC) before parsing, so that literals match regardless
+// of whether the source used precomposed or decomposed characters. Literals
+// in the grammar should be written in the same form.
+func WithNormalization(form norm.Form) parsec.ParseOption {
+	return parsec.WithRewrite(func(src string, m *parsec.SourceMapper) error {
+		var it norm.Iter
+		it.InitString(form, src)
+		for !it.Done() {
+			orig := it.Pos()
+			m.Emit(string(it.Next()), orig)
+		}
+		return nil
+	})
+}
+
+// WithEncoding transcodes input in a legacy encoding such as
+// charmap.Windows1252 or japanese.ShiftJIS to UTF-8 before parsing. Errors
+// and positions still report byte offsets in the original input.
+func WithEncoding(enc encoding.Encoding) parsec.ParseOption {
+	return parsec.WithRewrite(func(src string, m *parsec.SourceMapper) error {
+		var buf [64]byte
+		dec := enc.NewDecoder()
+		// Feed the decoder one character at a time so that every decoded
+		// rune can be mapped back to the bytes it came from.
+		for i, n := 0, 1; i < len(src); {
+			end := i + n
+			if end > len(src) {
+				end = len(src)
+			}
+			nDst, nSrc, err := dec.Transform(buf[:], []byte(src[i:end]), end == len(src))
+			if err == transform.ErrShortSrc && nSrc == 0 && end < len(src) {
+				n++
+				continue
+			}
+			if err != nil && err != transform.ErrShortSrc {
+				return parsec.ParseErr{Offset: i, Reason: fmt.Sprintf("Invalid %v input: %v", enc, err)}
+			}
+			m.Emit(string(buf[:nDst]), i)
+			i, n = i+nSrc, 1
+		}
+		return nil
+	})
+}
@@ -0,0 +1,299 @@
+package parsec
+
+import "time"
+
+var durationUnits = []string{"ns", "us", "µs", "μs", "ms", "s", "m", "h"}
+
+// Duration parses a Go-style duration such as 1h30m, 250ms or -2.5s and
+// returns a time.Duration.
+func Duration(st *ParseState) (interface{}, error) {
+	start, begin := st.Position(), st.Pos
+	st.next(func(c byte) bool { return c == '+' || c == '-' })
+	for {
+		mark := st.Pos
+		st.skipWhile(isDigit)
+		if rest := st.peek(2); len(rest) > 1 && rest[0] == '.' && isDigit(rest[1]) {
+			st.Pos++
+			st.skipWhile(isDigit)
+		}
+		if st.Pos == mark {
+			if mark == begin || isSign(st.Source[mark-1]) {
+				st.Pos = begin
+				return nil, st.trap("Expected duration")
+			}
+			break
+		}
+		unit := ""
+		for _, u := range durationUnits {
+			if st.hasPrefix(u) && len(u) > len(unit) {
+				unit = u
+			}
+		}
+		if unit == "" {
+			if st.Source[mark:st.Pos] == "0" && mark == begin {
+				break
+			}
+			return nil, st.trap("Missing unit in duration")
+		}
+		st.Pos += len(unit)
+	}
+	d, err := time.ParseDuration(st.Source[begin:st.Pos])
+	if err != nil {
+		return nil, st.trapAt(start, "Duration %s out of range", st.Source[begin:st.Pos])
+	}
+	return d, nil
+}
+
+func isSign(c byte) bool {
+	return c == '+' || c == '-'
+}
+
+// fixedDigits consumes exactly n decimal digits and returns their value,
+// failing with an error naming the component otherwise.
+func (st *ParseState) fixedDigits(n int, component string) (int, error) {
+	v := 0
+	for i := 0; i < n; i++ {
+		if st.atEnd(st.Pos+i) || !isDigit(st.Source[st.Pos+i]) {
+			return 0, st.trap("Expected %d-digit %s", n, component)
+		}
+		v = v*10 + int(st.Source[st.Pos+i]-'0')
+	}
+	st.Pos += n
+	return v, nil
+}
+
+// boundedDigits is fixedDigits with a range check reported at the start of
+// the component.
+func (st *ParseState) boundedDigits(n int, component string, min, max int) (int, error) {
+	pos := st.Position()
+	v, err := st.fixedDigits(n, component)
+	if err == nil && (v < min || v > max) {
+		err = st.trapAt(pos, "Invalid %s %0*d", component, n, v)
+	}
+	return v, err
+}
+
+func (st *ParseState) expectByte(c byte, what string) error {
+	if _, ok := st.next(func(b byte) bool { return b == c }); !ok {
+		return st.trap("Expected '%c' %s", c, what)
+	}
+	return nil
+}
+
+// Timestamp parses an RFC 3339 timestamp such as 2006-01-02T15:04:05.999Z
+// or 2006-01-02T15:04:05+07:00, or a date on its own, and returns a
+// time.Time. A space may separate the date and time as permitted by RFC
+// 3339, and times without an offset are taken to be in UTC, as are dates.
+// Errors name the malformed component and point at it.
+func Timestamp(st *ParseState) (interface{}, error) {
+	if st.atEnd(st.Pos) || !isDigit(st.Source[st.Pos]) {
+		return nil, st.trap("Expected timestamp")
+	}
+	year, err := st.fixedDigits(4, "year")
+	if err != nil {
+		return nil, err
+	}
+	if err := st.expectByte('-', "after year"); err != nil {
+		return nil, err
+	}
+	month, err := st.boundedDigits(2, "month", 1, 12)
+	if err != nil {
+		return nil, err
+	}
+	if err := st.expectByte('-', "after month"); err != nil {
+		return nil, err
+	}
+	dayPos := st.Position()
+	day, err := st.fixedDigits(2, "day")
+	if err != nil {
+		return nil, err
+	}
+	if last := time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day(); day < 1 || day > last {
+		return nil, st.trapAt(dayPos, "Invalid day %02d", day)
+	}
+	rest := st.peek(2)
+	if len(rest) < 2 || !(rest[0] == 'T' || rest[0] == 't' || rest[0] == ' ') || !isDigit(rest[1]) {
+		return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC), nil
+	}
+	st.Pos++
+	hour, minute, second, err := st.clockTime()
+	if err != nil {
+		return nil, err
+	}
+	nsec := 0
+	if _, ok := st.next(func(c byte) bool { return c == '.' }); ok {
+		begin := st.Pos
+		if st.skipWhile(isDigit) == 0 {
+			return nil, st.trap("Expected fractional seconds")
+		}
+		for i, scale := begin, int(1e8); i < st.Pos && scale > 0; i, scale = i+1, scale/10 {
+			nsec += int(st.Source[i]-'0') * scale
+		}
+	}
+	loc := time.UTC
+	if _, ok := st.next(func(c byte) bool { return c == 'Z' || c == 'z' }); !ok {
+		if c, ok := st.next(isSign); ok {
+			offHour, err := st.boundedDigits(2, "offset hour", 0, 23)
+			if err != nil {
+				return nil, err
+			}
+			if err := st.expectByte(':', "in offset"); err != nil {
+				return nil, err
+			}
+			offMinute, err := st.boundedDigits(2, "offset minute", 0, 59)
+			if err != nil {
+				return nil, err
+			}
+			offset := offHour*3600 + offMinute*60
+			if c == '-' {
+				offset = -offset
+			}
+			loc = time.FixedZone("", offset)
+		}
+	}
+	return time.Date(year, time.Month(month), day, hour, minute, second, nsec, loc), nil
+}
+
+var shortDayNames = []string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+var longDayNames = []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
+var monthNames = []string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
+
+func (st *ParseState) word(words []string, component string) (int, error) {
+	for i, w := range words {
+		if st.hasPrefix(w) {
+			st.Pos += len(w)
+			return i, nil
+		}
+	}
+	return -1, st.trap("Expected %s", component)
+}
+
+func (st *ParseState) literal(s string) error {
+	_, err := String(s)(st)
+	return err
+}
+
+func (st *ParseState) clockTime() (hour, minute, second int, err error) {
+	if hour, err = st.boundedDigits(2, "hour", 0, 23); err != nil {
+		return
+	}
+	if err = st.expectByte(':', "after hour"); err != nil {
+		return
+	}
+	if minute, err = st.boundedDigits(2, "minute", 0, 59); err != nil {
+		return
+	}
+	if err = st.expectByte(':', "after minute"); err != nil {
+		return
+	}
+	second, err = st.boundedDigits(2, "second", 0, 60)
+	return
+}
+
+// HTTPDate parses a date in any of the three formats allowed in HTTP
+// headers and returns it as a UTC time.Time:
+//
+//	Sun, 06 Nov 1994 08:49:37 GMT    (IMF-fixdate)
+//	Sunday, 06-Nov-94 08:49:37 GMT   (RFC 850)
+//	Sun Nov  6 08:49:37 1994         (asctime)
+func HTTPDate(st *ParseState) (interface{}, error) {
+	var year, month, day, hour, minute, second int
+	var dayPos Position
+	var err error
+	if _, err = st.word(longDayNames, "day name"); err == nil {
+		if err = st.literal(", "); err != nil {
+			return nil, err
+		}
+		dayPos = st.Position()
+		if day, err = st.fixedDigits(2, "day"); err != nil {
+			return nil, err
+		}
+		if err = st.expectByte('-', "after day"); err != nil {
+			return nil, err
+		}
+		if month, err = st.word(monthNames, "month name"); err != nil {
+			return nil, err
+		}
+		if err = st.expectByte('-', "after month"); err != nil {
+			return nil, err
+		}
+		if year, err = st.fixedDigits(2, "year"); err != nil {
+			return nil, err
+		}
+		if year += 1900; year < 1969 {
+			year += 100
+		}
+		if err = st.expectByte(' ', "after year"); err != nil {
+			return nil, err
+		}
+		if hour, minute, second, err = st.clockTime(); err != nil {
+			return nil, err
+		}
+		err = st.literal(" GMT")
+	} else if _, err = st.word(shortDayNames, "day name"); err != nil {
+		return nil, st.trap("Expected HTTP date")
+	} else if _, ok := st.next(func(c byte) bool { return c == ',' }); ok {
+		if err = st.expectByte(' ', "after day name"); err != nil {
+			return nil, err
+		}
+		dayPos = st.Position()
+		if day, err = st.fixedDigits(2, "day"); err != nil {
+			return nil, err
+		}
+		if err = st.expectByte(' ', "after day"); err != nil {
+			return nil, err
+		}
+		if month, err = st.word(monthNames, "month name"); err != nil {
+			return nil, err
+		}
+		if err = st.expectByte(' ', "after month"); err != nil {
+			return nil, err
+		}
+		if year, err = st.fixedDigits(4, "year"); err != nil {
+			return nil, err
+		}
+		if err = st.expectByte(' ', "after year"); err != nil {
+			return nil, err
+		}
+		if hour, minute, second, err = st.clockTime(); err != nil {
+			return nil, err
+		}
+		err = st.literal(" GMT")
+	} else {
+		if err = st.expectByte(' ', "after day name"); err != nil {
+			return nil, err
+		}
+		if month, err = st.word(monthNames, "month name"); err != nil {
+			return nil, err
+		}
+		if err = st.expectByte(' ', "after month"); err != nil {
+			return nil, err
+		}
+		dayPos = st.Position()
+		if _, ok := st.next(func(c byte) bool { return c == ' ' }); ok {
+			day, err = st.fixedDigits(1, "day")
+		} else {
+			day, err = st.fixedDigits(2, "day")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err = st.expectByte(' ', "after day"); err != nil {
+			return nil, err
+		}
+		if hour, minute, second, err = st.clockTime(); err != nil {
+			return nil, err
+		}
+		if err = st.expectByte(' ', "after time"); err != nil {
+			return nil, err
+		}
+		year, err = st.fixedDigits(4, "year")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if last := time.Date(year, time.Month(month+2), 0, 0, 0, 0, 0, time.UTC).Day(); day < 1 || day > last {
+		return nil, st.trapAt(dayPos, "Invalid day %02d", day)
+	}
+	return time.Date(year, time.Month(month+1), day, hour, minute, second, 0, time.UTC), nil
+}
@@ -0,0 +1,323 @@
+// Package toml parses a practical subset of TOML: tables, arrays of tables,
+// dotted keys, basic and literal strings, integers, floats, booleans,
+// offset and local date-times, arrays and inline tables. Multi-line basic
+// strings and local times are not supported.
+//
+// The grammar keeps the document being built in the parse's user state,
+// and recovers from an error by skipping to the next line, so that a
+// single parse reports every malformed line.
+package toml
+
+import (
+	"math"
+	"strings"
+
+	"parsec"
+)
+
+// Errors lists the errors found in a document, in input order.
+type Errors []parsec.ParseErr
+
+func (errs Errors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the errors, for errors.As and errors.Is.
+func (errs Errors) Unwrap() []error {
+	unwrapped := make([]error, len(errs))
+	for i, err := range errs {
+		unwrapped[i] = err
+	}
+	return unwrapped
+}
+
+// document is the user state of a parse.
+type document struct {
+	root    map[string]interface{}
+	current map[string]interface{}
+	headers map[string]bool
+}
+
+type keyValue struct {
+	key   []string
+	span  parsec.Span
+	value interface{}
+}
+
+var ws = parsec.Spaces
+var comment = parsec.Char('#').Then(parsec.SkipMany(parsec.NoneOf([]byte("\r\n"))))
+
+// wsNewlines skips whitespace, newlines and comments inside arrays.
+var wsNewlines = parsec.SkipMany(parsec.Space.Or(parsec.Newline).Or(comment))
+
+var basicString = parsec.QuotedString(parsec.QuoteOptions{
+	Quotes:     `"`,
+	Escape:     '\\',
+	Escapes:    map[rune]string{'b': "\b", 't': "\t", 'n': "\n", 'f': "\f", 'r': "\r"},
+	HexEscapes: true,
+})
+
+// literalString parses a single-line literal string.
+func literalString(st *parsec.ParseState) (interface{}, error) {
+	start := st.Position()
+	x, err := parsec.RawString("'", "'")(st)
+	if err == nil && strings.ContainsAny(x.(string), "\r\n") {
+		return nil, st.ErrorAt(start, "Unterminated literal string")
+	}
+	return x, err
+}
+
+// multilineLiteral parses a multi-line literal string, trimming a newline
+// immediately after the opening delimiter.
+var multilineLiteral = parsec.RawString("'''", "'''").Bind(func(x interface{}) parsec.Parser {
+	s := strings.TrimPrefix(x.(string), "\n")
+	return parsec.Return(strings.TrimPrefix(s, "\r\n"))
+})
+
+var bareKey = parsec.Many1(parsec.OneOf([]byte("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789_-"))).ToString()
+
+var simpleKey = bareKey.Or(basicString).Or(literalString).Label("key")
+
+var dottedKey = simpleKey.SepBy1(parsec.Try(ws.Then(parsec.Char('.'))).Then(ws)).WithSpan().Bind(func(x interface{}) parsec.Parser {
+	s := x.(parsec.Spanned)
+	var key []string
+	for _, k := range s.Value.([]interface{}) {
+		key = append(key, k.(string))
+	}
+	return parsec.Return(keyValue{key: key, span: s.Span})
+})
+
+// datetime parses an offset or local date-time, or a local date.
+func datetime(st *parsec.ParseState) (interface{}, error) {
+	rest := st.Source[st.Pos:]
+	if len(rest) < 5 || rest[4] != '-' || strings.IndexFunc(rest[:4], func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+		return parsec.Fail("Expected date")(st)
+	}
+	return parsec.Timestamp(st)
+}
+
+var numberOpts = parsec.NumberOptions{Underscores: true}
+
+var specialFloats = map[string]float64{
+	"inf": math.Inf(1), "+inf": math.Inf(1), "-inf": math.Inf(-1),
+	"nan": math.NaN(), "+nan": math.NaN(), "-nan": math.NaN(),
+}
+
+// number parses an integer, which may be written in hex, octal or binary,
+// or a float, including inf and nan.
+func number(st *parsec.ParseState) (interface{}, error) {
+	token := st.Source[st.Pos:]
+	if n := strings.IndexAny(token, " \t\r\n,]}#"); n >= 0 {
+		token = token[:n]
+	}
+	if v, ok := specialFloats[token]; ok {
+		st.Pos += len(token)
+		return v, nil
+	}
+	digits := strings.TrimLeft(token, "+-")
+	if digits == "" || digits[0] < '0' || digits[0] > '9' {
+		return parsec.Fail("Expected value")(st)
+	}
+	if len(digits) > 1 && digits[0] == '0' && strings.ContainsAny(digits[1:2], "xob") {
+		x, err := parsec.RadixIntegerWith(numberOpts)(st)
+		if err != nil {
+			return nil, err
+		}
+		return x.(parsec.RadixInt).Value, nil
+	}
+	if strings.ContainsAny(digits, ".eE") {
+		x, err := parsec.FloatWith(numberOpts)(st)
+		if err != nil {
+			return nil, err
+		}
+		return x.(parsec.FloatLit).Value, nil
+	}
+	return parsec.IntegerWith(numberOpts)(st)
+}
+
+// grammar returns a parser for one line of a document, or several for
+// arrays spanning lines, which records its table or key in the document.
+func grammar() parsec.Parser {
+	var value parsec.Parser
+	valueRef := parsec.Parser(func(st *parsec.ParseState) (interface{}, error) {
+		return value(st)
+	})
+
+	keyval := dottedKey.Bind(func(x interface{}) parsec.Parser {
+		return ws.Then(parsec.Char('=').Label("'='")).Then(ws).Then(valueRef).Bind(func(v interface{}) parsec.Parser {
+			kv := x.(keyValue)
+			kv.value = v
+			return parsec.Return(kv)
+		})
+	})
+
+	item := valueRef.Bind(func(x interface{}) parsec.Parser {
+		return wsNewlines.Then(parsec.Return(x))
+	})
+	array := wsNewlines.Then(item.SepEndBy(parsec.Char(',').Then(wsNewlines))).
+		Between(parsec.Char('['), parsec.Char(']').Label("']'"))
+
+	inlineTable := ws.Then(keyval.Bind(func(x interface{}) parsec.Parser {
+		return ws.Then(parsec.Return(x))
+	}).SepBy(parsec.Char(',').Then(ws))).Between(parsec.Char('{'), parsec.Char('}').Label("'}'")).Bind(func(x interface{}) parsec.Parser {
+		return func(st *parsec.ParseState) (interface{}, error) {
+			table := make(map[string]interface{})
+			for _, kv := range x.([]interface{}) {
+				if err := assign(st, table, kv.(keyValue)); err != nil {
+					return nil, err
+				}
+			}
+			return table, nil
+		}
+	})
+
+	value = parsec.Either(multilineLiteral, literalString).
+		Or(basicString).
+		Or(parsec.String("true").Then(parsec.Return(true))).
+		Or(parsec.String("false").Then(parsec.Return(false))).
+		Or(datetime).
+		Or(number).
+		Or(array).
+		Or(inlineTable).
+		Label("value")
+
+	arrayTable := dottedKey.Between(parsec.String("[[").Then(ws), ws.Then(parsec.String("]]").Label("']]'"))).Bind(func(x interface{}) parsec.Parser {
+		return func(st *parsec.ParseState) (interface{}, error) {
+			return nil, st.User.(*document).appendTable(st, x.(keyValue))
+		}
+	})
+	table := dottedKey.Between(parsec.Char('[').Then(ws), ws.Then(parsec.Char(']').Label("']'"))).Bind(func(x interface{}) parsec.Parser {
+		return func(st *parsec.ParseState) (interface{}, error) {
+			return nil, st.User.(*document).openTable(st, x.(keyValue))
+		}
+	})
+	pair := keyval.Bind(func(x interface{}) parsec.Parser {
+		return func(st *parsec.ParseState) (interface{}, error) {
+			return nil, assign(st, st.User.(*document).current, x.(keyValue))
+		}
+	})
+
+	return ws.Then(parsec.Skip(arrayTable.Or(table).Or(pair))).Then(ws).Then(parsec.Skip(comment)).Then(parsec.Eol.Label("end of line"))
+}
+
+var line = grammar()
+
+// assign sets the value at a dotted key within table, creating
+// intermediate tables as needed.
+func assign(st *parsec.ParseState, table map[string]interface{}, kv keyValue) error {
+	for _, k := range kv.key[:len(kv.key)-1] {
+		next, err := descend(st, table, k, kv)
+		if err != nil {
+			return err
+		}
+		table = next
+	}
+	last := kv.key[len(kv.key)-1]
+	if _, ok := table[last]; ok {
+		return st.ErrorAt(kv.span.Start, "Duplicate key %s", strings.Join(kv.key, "."))
+	}
+	table[last] = kv.value
+	return nil
+}
+
+// descend returns the table named k within table, creating it if needed.
+// For an array of tables it returns the last element.
+func descend(st *parsec.ParseState, table map[string]interface{}, k string, kv keyValue) (map[string]interface{}, error) {
+	switch v := table[k].(type) {
+	case nil:
+		next := make(map[string]interface{})
+		table[k] = next
+		return next, nil
+	case map[string]interface{}:
+		return v, nil
+	case []interface{}:
+		if len(v) > 0 {
+			if next, ok := v[len(v)-1].(map[string]interface{}); ok {
+				return next, nil
+			}
+		}
+	}
+	return nil, st.ErrorAt(kv.span.Start, "Key %s is not a table", k)
+}
+
+func (doc *document) openTable(st *parsec.ParseState, kv keyValue) error {
+	table := doc.root
+	for _, k := range kv.key {
+		next, err := descend(st, table, k, kv)
+		if err != nil {
+			return err
+		}
+		table = next
+	}
+	name := strings.Join(kv.key, "\x00")
+	if doc.headers[name] {
+		return st.ErrorAt(kv.span.Start, "Duplicate table [%s]", strings.Join(kv.key, "."))
+	}
+	doc.headers[name] = true
+	doc.current = table
+	return nil
+}
+
+func (doc *document) appendTable(st *parsec.ParseState, kv keyValue) error {
+	table := doc.root
+	for _, k := range kv.key[:len(kv.key)-1] {
+		next, err := descend(st, table, k, kv)
+		if err != nil {
+			return err
+		}
+		table = next
+	}
+	last := kv.key[len(kv.key)-1]
+	next := make(map[string]interface{})
+	switch v := table[last].(type) {
+	case nil:
+		table[last] = []interface{}{next}
+	case []interface{}:
+		table[last] = append(v, next)
+	default:
+		return st.ErrorAt(kv.span.Start, "Key %s is not an array of tables", strings.Join(kv.key, "."))
+	}
+	doc.current = next
+	return nil
+}
+
+// Parse parses a TOML document. Values are string, int64, float64, bool,
+// time.Time, []interface{} and map[string]interface{}. If the document has
+// errors, Parse returns them all as Errors, along with the tables built
+// from the lines that parsed.
+func Parse(source string) (map[string]interface{}, error) {
+	root := make(map[string]interface{})
+	doc := &document{root: root, current: root, headers: make(map[string]bool)}
+	x, err := parsec.Parser(func(st *parsec.ParseState) (interface{}, error) {
+		var errs Errors
+		for st.Pos < len(st.Source) {
+			_, err := line(st)
+			if err == nil {
+				continue
+			}
+			pe, ok := err.(parsec.ParseErr)
+			if !ok {
+				return nil, err
+			}
+			errs = append(errs, pe)
+			if n := strings.IndexAny(st.Source[st.Pos:], "\r\n"); n >= 0 {
+				st.Pos += n
+				parsec.Newline(st)
+			} else {
+				st.Pos = len(st.Source)
+			}
+		}
+		return errs, nil
+	}).Parse(source, parsec.WithUserState(doc))
+	if err != nil {
+		return nil, err
+	}
+	if errs := x.(Errors); len(errs) > 0 {
+		return root, errs
+	}
+	return root, nil
+}
@@ -0,0 +1,43 @@
+package parsec
+
+import (
+	"strings"
+)
+
+// UUID parses a UUID in canonical hyphenated form, optionally wrapped in
+// braces or prefixed with "urn:uuid:", and returns it as a [16]byte.
+func UUID(st *ParseState) (interface{}, error) {
+	var id [16]byte
+	braced := false
+	if strings.EqualFold(st.peek(9), "urn:uuid:") {
+		st.Pos += 9
+	} else if _, ok := st.next(func(c byte) bool { return c == '{' }); ok {
+		braced = true
+	}
+	n := 0
+	for i, group := range []int{4, 2, 2, 2, 6} {
+		if i > 0 {
+			if err := st.expectByte('-', "in UUID"); err != nil {
+				return nil, err
+			}
+		}
+		for j := 0; j < group*2; j++ {
+			if st.atEnd(st.Pos) {
+				return nil, st.trap("Unexpected end of UUID")
+			}
+			v := hexValue(st.Source[st.Pos])
+			if v < 0 {
+				return nil, st.trap("Invalid character '%c' in UUID", st.Source[st.Pos])
+			}
+			id[n/2] |= byte(v) << (4 * (1 - n%2))
+			n++
+			st.Pos++
+		}
+	}
+	if braced {
+		if err := st.expectByte('}', "closing UUID"); err != nil {
+			return nil, err
+		}
+	}
+	return id, nil
+}